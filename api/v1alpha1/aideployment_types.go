@@ -17,7 +17,6 @@ limitations under the License.
 package v1alpha1
 
 import (
-	"github.com/premAI-io/prem-operator/controllers/constants"
 	v1 "k8s.io/api/core/v1"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -46,6 +45,14 @@ type AIDeploymentSpec struct {
 	Ingress Ingress `json:"ingress,omitempty"`
 
 	Models []AIModel `json:"models,omitempty"`
+
+	// A ModelCache in the AIDeployment's namespace which the models are
+	// downloaded to once and loaded from, instead of being downloaded by each
+	// pod. The Deployment is only created, or updated, once the models are in
+	// the cache. The vllm, tgi, sglang, localai, llamacpp and triton engines
+	// support it.
+	// +optional
+	ModelCacheRef *v1.LocalObjectReference `json:"modelCacheRef,omitempty"`
 }
 
 type Service struct {
@@ -110,6 +117,9 @@ type Deployment struct {
 
 	// +optional
 	Replicas *int32 `json:"replicas,omitempty"`
+	// The pod every engine starts from, the engine's containers and volumes
+	// are added to it. Its scheduling settings, volumes, image pull secrets
+	// and containers are kept, the latter run alongside the engine.
 	// +optional
 	PodTemplate *v1.PodTemplateSpec `json:"template,omitempty"`
 
@@ -119,6 +129,20 @@ type Deployment struct {
 	ReadinessProbe *Probe `json:"readinessProbe,omitempty"`
 	// +optional
 	LivenessProbe *Probe `json:"livenessProbe,omitempty"`
+
+	// Splits each replica across several pods, for models which don't fit on
+	// the GPUs of one node. Only some engines support this.
+	// +optional
+	MultiNode *MultiNode `json:"multiNode,omitempty"`
+}
+
+// MultiNode describes a group of pods which serve one replica of the model.
+// The leader pod serves the API and the workers join it.
+type MultiNode struct {
+	// The number of pods in each group, including the leader. Each pod gets the
+	// resources of the deployment.
+	// +kubebuilder:validation:Minimum=2
+	Size int32 `json:"size"`
 }
 
 type Ingress struct {
@@ -130,6 +154,8 @@ type Ingress struct {
 
 type Endpoint struct {
 	Domain string `json:"domain"`
+	// The port of the Service which requests to Domain are sent to, one of
+	// the engine's ports. Defaults to the engine's HTTP port.
 	// +optional
 	Port int32 `json:"port,omitempty"`
 }
@@ -143,6 +169,10 @@ const (
 	AIEngineNameGeneric      AIEngineName = "generic"
 	AIEngineNameDeepSpeedMii AIEngineName = "deepspeed-mii"
 	AIEngineNameTriton       AIEngineName = "triton"
+	AIEngineNameTgi          AIEngineName = "tgi"
+	AIEngineNameOllama       AIEngineName = "ollama"
+	AIEngineNameLlamacpp     AIEngineName = "llamacpp"
+	AIEngineNameSglang       AIEngineName = "sglang"
 )
 
 type AIEngine struct {
@@ -161,16 +191,26 @@ type AIModel struct {
 
 // AIDeploymentStatus defines the observed state of AIDeployment
 type AIDeploymentStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
-	Status constants.Status `json:"status,omitempty"`
-	// Error message if the deployment failed, otherwise it is empty
+	// The generation of the AIDeployment that was last reconciled
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// What has been done to bring the deployment up, see the
+	// Condition* constants in controllers/constants for the types
 	// +optional
-	ErrMsg string `json:"errMsg,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	// +patchStrategy=merge
+	// +patchMergeKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Engine",type=string,JSONPath=`.spec.engine.name`
+//+kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="DeploymentAvailable")].status`
+//+kubebuilder:printcolumn:name="Progressing",type=string,JSONPath=`.status.conditions[?(@.type=="Progressing")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // AIDeployment is the Schema for the AIDeployment API
 type AIDeployment struct {
@@ -0,0 +1,105 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HTTP paths used to probe the engine container, probes without a path are
+// not added
+type AIEngineTemplateProbes struct {
+	// +optional
+	Startup string `json:"startup,omitempty"`
+	// +optional
+	Readiness string `json:"readiness,omitempty"`
+	// +optional
+	Liveness string `json:"liveness,omitempty"`
+}
+
+// AIEngineTemplateSpec defines an engine which runs a single container
+type AIEngineTemplateSpec struct {
+	Image string `json:"image"`
+
+	// The port the engine serves on
+	// +kubebuilder:default=8000
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// Overrides the entrypoint of the image
+	// +optional
+	Command []string `json:"command,omitempty"`
+
+	// Arguments for the engine, each one is a Go text/template. The template
+	// is given the first model as .Model, all models as .Models, the engine
+	// options as .Options and the port as .Port. A model has the fields
+	// Name, Variant, Uri, DataType and Quantization. Arguments which render
+	// to an empty string are dropped, so optional flags can be written as
+	// "{{ with .Model.DataType }}--dtype={{ . }}{{ end }}".
+	// +optional
+	Args []string `json:"args,omitempty"`
+
+	// +optional
+	Env []v1.EnvVar `json:"env,omitempty"`
+
+	// +optional
+	Probes AIEngineTemplateProbes `json:"probes,omitempty"`
+
+	// Where the engine's variants are found in an AIModelMap, under
+	// spec.engines. Defaults to the name of the template.
+	// +optional
+	ModelMapKey string `json:"modelMapKey,omitempty"`
+
+	// The maximum number of models the engine can serve, zero means there
+	// is no limit
+	// +optional
+	MaxModels int `json:"maxModels,omitempty"`
+}
+
+// AIEngineTemplateStatus defines the observed state of AIEngineTemplate
+type AIEngineTemplateStatus struct {
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`
+//+kubebuilder:printcolumn:name="Port",type=integer,JSONPath=`.spec.port`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AIEngineTemplate is the Schema for the aienginetemplates API
+type AIEngineTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AIEngineTemplateSpec   `json:"spec,omitempty"`
+	Status AIEngineTemplateStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AIEngineTemplateList contains a list of AIEngineTemplate
+type AIEngineTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AIEngineTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AIEngineTemplate{}, &AIEngineTemplateList{})
+}
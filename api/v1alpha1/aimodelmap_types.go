@@ -17,6 +17,10 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"slices"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -44,7 +48,25 @@ const (
 // Information needed to use a model
 // NOTE: Remember to update the mergeModelSpecs function in resolve.go when adding fields
 type AIModelSpec struct {
+	// Where the model is fetched from. Besides what the engine accepts, e.g.
+	// a Hugging Face model id, this can be an http(s), s3, gs, hf or oci URI
+	// which the operator's downloader fetches into the pod.
+	// +optional
 	Uri string `json:"uri,omitempty"`
+	// The hex encoded SHA-256 of the file at Uri, or of the archive for
+	// engines which download an archive. Engines which download the model
+	// with the operator's downloader check the file against it.
+	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{64}$`
+	// +optional
+	Sha256 string `json:"sha256,omitempty"`
+
+	// A Secret with the credentials needed to fetch the model, in the
+	// namespace of the AIDeployment. Its keys are given as env vars to the
+	// init container which downloads the model, see the model URIs guide for
+	// the names. Engines which fetch the model from the Hugging Face Hub
+	// themselves are only given its HF_TOKEN key.
+	// +optional
+	CredentialsSecretRef *v1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
 	// +optional
 	Quantization AIModelQuantization `json:"quantization,omitempty"`
 	// +optional
@@ -53,6 +75,40 @@ type AIModelSpec struct {
 	// Config file particular to the engine e.g. a LocalAI model specification
 	// +optional
 	EngineConfigFile string `json:"engineConfigFile,omitempty"`
+
+	// The maximum number of tokens in the model's context, if the engine
+	// allows it to be set
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ContextSize int32 `json:"contextSize,omitempty"`
+
+	// The URI of the base model this is a LoRA adapter for. Engines which
+	// support adapters serve them alongside the base model, which must also be
+	// one of the AIDeployment's models.
+	// +optional
+	AdapterOf string `json:"adapterOf,omitempty"`
+
+	// The backend the engine loads the model with, if it has several e.g.
+	// llama-cpp or transformers for LocalAI
+	// +optional
+	Backend string `json:"backend,omitempty"`
+
+	// Names of the prompt templates, for engines which take them by name
+	// +optional
+	Templates *AIModelTemplates `json:"templates,omitempty"`
+}
+
+// AIModelTemplates names the templates used to build a prompt for each kind
+// of request
+type AIModelTemplates struct {
+	// +optional
+	Chat string `json:"chat,omitempty"`
+	// +optional
+	ChatMessage string `json:"chatMessage,omitempty"`
+	// +optional
+	Completion string `json:"completion,omitempty"`
+	// +optional
+	Function string `json:"function,omitempty"`
 }
 
 type AIModelMapReference struct {
@@ -68,12 +124,97 @@ type AIModelVariant struct {
 	AIModelSpec `json:",inline"`
 }
 
+// Keys of the variant lists in AIModelMapSpec, engines are registered with
+// one of these or the key of their entry in AIModelMapSpec.Engines
+const (
+	AIModelMapKeyLocalai      = "localai"
+	AIModelMapKeyVllm         = "vllm"
+	AIModelMapKeyDeepSpeedMii = "deepspeed-mii"
+	AIModelMapKeyTensorRT     = "tensor_rt"
+	AIModelMapKeyTgi          = "tgi"
+	AIModelMapKeyOllama       = "ollama"
+	AIModelMapKeyLlamacpp     = "llamacpp"
+	AIModelMapKeySglang       = "sglang"
+)
+
+// aiModelMapOwnKeys are the keys which have a field in AIModelMapSpec
+var aiModelMapOwnKeys = []string{
+	AIModelMapKeyLocalai,
+	AIModelMapKeyVllm,
+	AIModelMapKeyDeepSpeedMii,
+	AIModelMapKeyTensorRT,
+	AIModelMapKeyTgi,
+	AIModelMapKeyOllama,
+	AIModelMapKeyLlamacpp,
+	AIModelMapKeySglang,
+}
+
+// IsAIModelMapOwnKey is true if the variants for key have their own field in
+// AIModelMapSpec instead of being in AIModelMapSpec.Engines
+func IsAIModelMapOwnKey(key string) bool {
+	return slices.Contains(aiModelMapOwnKeys, key)
+}
+
 // AIModelMapSpec defines the desired state of AIModelMap
 type AIModelMapSpec struct {
 	Localai      []AIModelVariant `json:"localai,omitempty"`
 	Vllm         []AIModelVariant `json:"vllm,omitempty"`
 	DeepSpeedMii []AIModelVariant `json:"deepspeed-mii,omitempty"`
 	TensorRT     []AIModelVariant `json:"tensor_rt,omitempty"`
+	Tgi          []AIModelVariant `json:"tgi,omitempty"`
+	Ollama       []AIModelVariant `json:"ollama,omitempty"`
+	Llamacpp     []AIModelVariant `json:"llamacpp,omitempty"`
+	Sglang       []AIModelVariant `json:"sglang,omitempty"`
+
+	// Variants for engines which don't have their own field, keyed by the
+	// engine's model map key
+	// +optional
+	Engines map[string][]AIModelVariant `json:"engines,omitempty"`
+}
+
+// Variants returns the variants stored under an engine's model map key
+func (s *AIModelMapSpec) Variants(key string) []AIModelVariant {
+	switch key {
+	case AIModelMapKeyLocalai:
+		return s.Localai
+	case AIModelMapKeyVllm:
+		return s.Vllm
+	case AIModelMapKeyDeepSpeedMii:
+		return s.DeepSpeedMii
+	case AIModelMapKeyTensorRT:
+		return s.TensorRT
+	case AIModelMapKeyTgi:
+		return s.Tgi
+	case AIModelMapKeyOllama:
+		return s.Ollama
+	case AIModelMapKeyLlamacpp:
+		return s.Llamacpp
+	case AIModelMapKeySglang:
+		return s.Sglang
+	default:
+		return s.Engines[key]
+	}
+}
+
+// VariantKeys returns the model map keys which have variants. Entries in
+// Engines are ignored if the key has its own field.
+func (s *AIModelMapSpec) VariantKeys() []string {
+	keys := []string{}
+	for _, k := range aiModelMapOwnKeys {
+		if len(s.Variants(k)) > 0 {
+			keys = append(keys, k)
+		}
+	}
+
+	engineKeys := make([]string, 0, len(s.Engines))
+	for k := range s.Engines {
+		if !IsAIModelMapOwnKey(k) {
+			engineKeys = append(engineKeys, k)
+		}
+	}
+	sort.Strings(engineKeys)
+
+	return append(keys, engineKeys...)
 }
 
 // AIModelMapStatus defines the observed state of AIModelMap
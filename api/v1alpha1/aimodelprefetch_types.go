@@ -0,0 +1,142 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AIModelPrefetchSpec selects the AIModelMap variants to download and the
+// nodes to download them onto
+type AIModelPrefetchSpec struct {
+	// The engine the variants are for, which is where they are found in the
+	// AIModelMaps. Only the vllm, tgi and sglang engines can load prefetched
+	// models.
+	Engine AIEngineName `json:"engine"`
+
+	// The namespace the download pods run in. The Secrets of the models'
+	// credentialsSecretRefs must be in it.
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// The variants to download, an AIModelMap without a namespace is in the
+	// namespace of the download pods
+	// +kubebuilder:validation:MinItems=1
+	Models []AIModelMapReference `json:"models"`
+
+	// The labels of the nodes to download the models onto, e.g. those set by
+	// an AutoNodeLabeler. Every node is selected if it is empty.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations of the download pods, e.g. for the taints of GPU nodes
+	// +optional
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
+
+	// Env of the download pods, e.g. the endpoint of an S3 compatible store.
+	// Credentials are given by each model's credentialsSecretRef.
+	// +optional
+	Env []v1.EnvVar `json:"env,omitempty"`
+}
+
+// +enum
+type NodePrefetchPhase string
+
+const (
+	NodePrefetchPhasePending     NodePrefetchPhase = "Pending"
+	NodePrefetchPhaseDownloading NodePrefetchPhase = "Downloading"
+	NodePrefetchPhaseReady       NodePrefetchPhase = "Ready"
+	NodePrefetchPhaseFailed      NodePrefetchPhase = "Failed"
+)
+
+// NodePrefetchStatus is the progress of the download onto a node
+type NodePrefetchStatus struct {
+	Node  string            `json:"node"`
+	Phase NodePrefetchPhase `json:"phase"`
+	// The number of models downloaded onto the node
+	// +optional
+	ModelsReady int32 `json:"modelsReady,omitempty"`
+	// Why the download failed
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// AIModelPrefetchStatus defines the observed state of AIModelPrefetch
+type AIModelPrefetchStatus struct {
+	// The generation of the AIModelPrefetch that was last reconciled
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Whether the models could be resolved, see the Condition* constants in
+	// controllers/constants for the types
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	// +patchStrategy=merge
+	// +patchMergeKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// The number of models being downloaded onto each node
+	// +optional
+	Models int32 `json:"models,omitempty"`
+	// The number of selected nodes and of those which have every model
+	// +optional
+	DesiredNodes int32 `json:"desiredNodes,omitempty"`
+	// +optional
+	ReadyNodes int32 `json:"readyNodes,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=node
+	Nodes []NodePrefetchStatus `json:"nodes,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Engine",type=string,JSONPath=`.spec.engine`
+//+kubebuilder:printcolumn:name="Models",type=integer,JSONPath=`.status.models`
+//+kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.status.desiredNodes`
+//+kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyNodes`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AIModelPrefetch downloads AIModelMap variants onto nodes with a DaemonSet,
+// so that they are there before an engine's pod is scheduled. Engines which
+// use a prefetched model prefer the nodes which have it. It is cluster scoped
+// as it writes to every node it selects and labels them, so only cluster
+// admins can create one.
+type AIModelPrefetch struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AIModelPrefetchSpec   `json:"spec,omitempty"`
+	Status AIModelPrefetchStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AIModelPrefetchList contains a list of AIModelPrefetch
+type AIModelPrefetchList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AIModelPrefetch `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AIModelPrefetch{}, &AIModelPrefetchList{})
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ModelCacheSpec defines the volume models are stored in
+type ModelCacheSpec struct {
+	// The size of the PersistentVolumeClaim the models are stored in, it can
+	// be increased if the storage class allows volume expansion
+	Size resource.Quantity `json:"size"`
+
+	// The storage class of the claim, the cluster's default if it is not set
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// ReadWriteMany shares the cache between the pods on every node.
+	// ReadWriteOnce keeps it on a single node, which is for volumes such as
+	// local volumes that schedule the pods using them onto their node.
+	// +kubebuilder:validation:Enum=ReadWriteMany;ReadWriteOnce
+	// +kubebuilder:default=ReadWriteMany
+	// +optional
+	AccessMode v1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
+}
+
+// +enum
+type CachedModelPhase string
+
+const (
+	CachedModelPhasePending     CachedModelPhase = "Pending"
+	CachedModelPhaseDownloading CachedModelPhase = "Downloading"
+	CachedModelPhaseReady       CachedModelPhase = "Ready"
+	CachedModelPhaseFailed      CachedModelPhase = "Failed"
+)
+
+// CachedModel is a resolved model which is downloaded into the cache
+type CachedModel struct {
+	// The directory the model is stored in, it identifies the model by its
+	// name, variant, URI and revision
+	Key string `json:"key"`
+	// The AIModelMap, or the AIDeployment for an inline model
+	Name    string `json:"name"`
+	Variant string `json:"variant"`
+	Uri     string `json:"uri"`
+	// The revision the URI names, e.g. of a Hugging Face repository, or the
+	// model's sha256
+	// +optional
+	Revision string `json:"revision,omitempty"`
+
+	Phase CachedModelPhase `json:"phase"`
+	// Why the download failed
+	// +optional
+	Message string `json:"message,omitempty"`
+	// The size of the model's files once it is downloaded
+	// +optional
+	Bytes int64 `json:"bytes,omitempty"`
+	// How many AIDeployments started using the model after it was
+	// downloaded, so didn't have to wait for it
+	// +optional
+	Hits int64 `json:"hits,omitempty"`
+	// The AIDeployments which use the model
+	// +optional
+	Deployments []string `json:"deployments,omitempty"`
+}
+
+// ModelCacheStatus defines the observed state of ModelCache
+type ModelCacheStatus struct {
+	// The generation of the ModelCache that was last reconciled
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=key
+	Models []CachedModel `json:"models,omitempty"`
+
+	// The sum of the models' hits
+	// +optional
+	Hits int64 `json:"hits,omitempty"`
+	// The sum of the models' bytes
+	// +optional
+	BytesStored int64 `json:"bytesStored,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Size",type=string,JSONPath=`.spec.size`
+//+kubebuilder:printcolumn:name="Hits",type=integer,JSONPath=`.status.hits`
+//+kubebuilder:printcolumn:name="Bytes",type=integer,JSONPath=`.status.bytesStored`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ModelCache is a PersistentVolumeClaim which the models of the
+// AIDeployments referring to it are downloaded to once, by a Job for each
+// model, and which their engines load the models from
+type ModelCache struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ModelCacheSpec   `json:"spec,omitempty"`
+	Status ModelCacheStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ModelCacheList contains a list of ModelCache
+type ModelCacheList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ModelCache `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ModelCache{}, &ModelCacheList{})
+}
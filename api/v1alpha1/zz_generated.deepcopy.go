@@ -32,7 +32,7 @@ func (in *AIDeployment) DeepCopyInto(out *AIDeployment) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIDeployment.
@@ -116,6 +116,11 @@ func (in *AIDeploymentSpec) DeepCopyInto(out *AIDeploymentSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ModelCacheRef != nil {
+		in, out := &in.ModelCacheRef, &out.ModelCacheRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIDeploymentSpec.
@@ -131,6 +136,13 @@ func (in *AIDeploymentSpec) DeepCopy() *AIDeploymentSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AIDeploymentStatus) DeepCopyInto(out *AIDeploymentStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIDeploymentStatus.
@@ -165,6 +177,128 @@ func (in *AIEngine) DeepCopy() *AIEngine {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIEngineTemplate) DeepCopyInto(out *AIEngineTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIEngineTemplate.
+func (in *AIEngineTemplate) DeepCopy() *AIEngineTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(AIEngineTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AIEngineTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIEngineTemplateList) DeepCopyInto(out *AIEngineTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AIEngineTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIEngineTemplateList.
+func (in *AIEngineTemplateList) DeepCopy() *AIEngineTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(AIEngineTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AIEngineTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIEngineTemplateProbes) DeepCopyInto(out *AIEngineTemplateProbes) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIEngineTemplateProbes.
+func (in *AIEngineTemplateProbes) DeepCopy() *AIEngineTemplateProbes {
+	if in == nil {
+		return nil
+	}
+	out := new(AIEngineTemplateProbes)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIEngineTemplateSpec) DeepCopyInto(out *AIEngineTemplateSpec) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.Probes = in.Probes
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIEngineTemplateSpec.
+func (in *AIEngineTemplateSpec) DeepCopy() *AIEngineTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AIEngineTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIEngineTemplateStatus) DeepCopyInto(out *AIEngineTemplateStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIEngineTemplateStatus.
+func (in *AIEngineTemplateStatus) DeepCopy() *AIEngineTemplateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AIEngineTemplateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AIModel) DeepCopyInto(out *AIModel) {
 	*out = *in
@@ -173,7 +307,7 @@ func (in *AIModel) DeepCopyInto(out *AIModel) {
 		*out = new(AIModelMapReference)
 		**out = **in
 	}
-	out.AIModelSpec = in.AIModelSpec
+	in.AIModelSpec.DeepCopyInto(&out.AIModelSpec)
 	if in.Options != nil {
 		in, out := &in.Options, &out.Options
 		*out = make(map[string]string, len(*in))
@@ -273,22 +407,76 @@ func (in *AIModelMapSpec) DeepCopyInto(out *AIModelMapSpec) {
 	if in.Localai != nil {
 		in, out := &in.Localai, &out.Localai
 		*out = make([]AIModelVariant, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.Vllm != nil {
 		in, out := &in.Vllm, &out.Vllm
 		*out = make([]AIModelVariant, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.DeepSpeedMii != nil {
 		in, out := &in.DeepSpeedMii, &out.DeepSpeedMii
 		*out = make([]AIModelVariant, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.TensorRT != nil {
 		in, out := &in.TensorRT, &out.TensorRT
 		*out = make([]AIModelVariant, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Tgi != nil {
+		in, out := &in.Tgi, &out.Tgi
+		*out = make([]AIModelVariant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Ollama != nil {
+		in, out := &in.Ollama, &out.Ollama
+		*out = make([]AIModelVariant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Llamacpp != nil {
+		in, out := &in.Llamacpp, &out.Llamacpp
+		*out = make([]AIModelVariant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Sglang != nil {
+		in, out := &in.Sglang, &out.Sglang
+		*out = make([]AIModelVariant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Engines != nil {
+		in, out := &in.Engines, &out.Engines
+		*out = make(map[string][]AIModelVariant, len(*in))
+		for key, val := range *in {
+			var outVal []AIModelVariant
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]AIModelVariant, len(*in))
+				for i := range *in {
+					(*in)[i].DeepCopyInto(&(*out)[i])
+				}
+			}
+			(*out)[key] = outVal
+		}
 	}
 }
 
@@ -317,9 +505,146 @@ func (in *AIModelMapStatus) DeepCopy() *AIModelMapStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIModelPrefetch) DeepCopyInto(out *AIModelPrefetch) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIModelPrefetch.
+func (in *AIModelPrefetch) DeepCopy() *AIModelPrefetch {
+	if in == nil {
+		return nil
+	}
+	out := new(AIModelPrefetch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AIModelPrefetch) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIModelPrefetchList) DeepCopyInto(out *AIModelPrefetchList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AIModelPrefetch, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIModelPrefetchList.
+func (in *AIModelPrefetchList) DeepCopy() *AIModelPrefetchList {
+	if in == nil {
+		return nil
+	}
+	out := new(AIModelPrefetchList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AIModelPrefetchList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIModelPrefetchSpec) DeepCopyInto(out *AIModelPrefetchSpec) {
+	*out = *in
+	if in.Models != nil {
+		in, out := &in.Models, &out.Models
+		*out = make([]AIModelMapReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIModelPrefetchSpec.
+func (in *AIModelPrefetchSpec) DeepCopy() *AIModelPrefetchSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AIModelPrefetchSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIModelPrefetchStatus) DeepCopyInto(out *AIModelPrefetchStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]NodePrefetchStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIModelPrefetchStatus.
+func (in *AIModelPrefetchStatus) DeepCopy() *AIModelPrefetchStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AIModelPrefetchStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AIModelSpec) DeepCopyInto(out *AIModelSpec) {
 	*out = *in
+	if in.CredentialsSecretRef != nil {
+		in, out := &in.CredentialsSecretRef, &out.CredentialsSecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = new(AIModelTemplates)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIModelSpec.
@@ -332,10 +657,25 @@ func (in *AIModelSpec) DeepCopy() *AIModelSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIModelTemplates) DeepCopyInto(out *AIModelTemplates) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIModelTemplates.
+func (in *AIModelTemplates) DeepCopy() *AIModelTemplates {
+	if in == nil {
+		return nil
+	}
+	out := new(AIModelTemplates)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AIModelVariant) DeepCopyInto(out *AIModelVariant) {
 	*out = *in
-	out.AIModelSpec = in.AIModelSpec
+	in.AIModelSpec.DeepCopyInto(&out.AIModelSpec)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIModelVariant.
@@ -465,6 +805,26 @@ func (in *AutoNodeLabelerStatus) DeepCopy() *AutoNodeLabelerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CachedModel) DeepCopyInto(out *CachedModel) {
+	*out = *in
+	if in.Deployments != nil {
+		in, out := &in.Deployments, &out.Deployments
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CachedModel.
+func (in *CachedModel) DeepCopy() *CachedModel {
+	if in == nil {
+		return nil
+	}
+	out := new(CachedModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Deployment) DeepCopyInto(out *Deployment) {
 	*out = *in
@@ -520,6 +880,11 @@ func (in *Deployment) DeepCopyInto(out *Deployment) {
 		*out = new(Probe)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.MultiNode != nil {
+		in, out := &in.MultiNode, &out.MultiNode
+		*out = new(MultiNode)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Deployment.
@@ -611,6 +976,138 @@ func (in *LabelSelectorRequirementApplyConfiguration) DeepCopy() *LabelSelectorR
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelCache) DeepCopyInto(out *ModelCache) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelCache.
+func (in *ModelCache) DeepCopy() *ModelCache {
+	if in == nil {
+		return nil
+	}
+	out := new(ModelCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ModelCache) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelCacheList) DeepCopyInto(out *ModelCacheList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ModelCache, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelCacheList.
+func (in *ModelCacheList) DeepCopy() *ModelCacheList {
+	if in == nil {
+		return nil
+	}
+	out := new(ModelCacheList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ModelCacheList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelCacheSpec) DeepCopyInto(out *ModelCacheSpec) {
+	*out = *in
+	out.Size = in.Size.DeepCopy()
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelCacheSpec.
+func (in *ModelCacheSpec) DeepCopy() *ModelCacheSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ModelCacheSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelCacheStatus) DeepCopyInto(out *ModelCacheStatus) {
+	*out = *in
+	if in.Models != nil {
+		in, out := &in.Models, &out.Models
+		*out = make([]CachedModel, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelCacheStatus.
+func (in *ModelCacheStatus) DeepCopy() *ModelCacheStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ModelCacheStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MultiNode) DeepCopyInto(out *MultiNode) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MultiNode.
+func (in *MultiNode) DeepCopy() *MultiNode {
+	if in == nil {
+		return nil
+	}
+	out := new(MultiNode)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePrefetchStatus) DeepCopyInto(out *NodePrefetchStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePrefetchStatus.
+func (in *NodePrefetchStatus) DeepCopy() *NodePrefetchStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePrefetchStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Probe) DeepCopyInto(out *Probe) {
 	*out = *in
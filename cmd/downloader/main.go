@@ -0,0 +1,55 @@
+// The downloader fetches a model file in the init container of an engine,
+// it is shipped in the operator image. The exit code says why it failed, see
+// the Exit constants in pkg/downloader.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/premAI-io/prem-operator/pkg/downloader"
+)
+
+func main() {
+	var (
+		o          downloader.Options
+		reportSize string
+	)
+	flag.StringVar(&o.URL, "url", "", "The URL to download, one of http(s)://, s3://, gs://, hf:// or oci://.")
+	flag.StringVar(&o.Output, "output", "", "The file to write, or the directory to extract or download several files into.")
+	flag.StringVar(&o.SHA256, "sha256", "", "The hex encoded SHA-256 of the download, it isn't checked if empty.")
+	flag.BoolVar(&o.Extract, "extract", false, "Extract the download as a tar archive, which may be gzipped.")
+	flag.StringVar(&o.From, "from", "", "A directory which may have the finished download already, the output is then a link to it.")
+	flag.IntVar(&o.Tries, "tries", downloader.DefaultTries, "How many times to try the download.")
+	flag.DurationVar(&o.Backoff, "backoff", downloader.DefaultBackoff, "The wait after the first failed try, it doubles after each try.")
+	flag.StringVar(&reportSize, "report-size", "", "Write the size in bytes of the output to this file, e.g. the termination message path.")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := downloader.Download(ctx, o); err != nil {
+		log.Error(err)
+		cancel()
+		os.Exit(downloader.ExitCode(err))
+	}
+
+	if reportSize == "" {
+		return
+	}
+
+	size, err := downloader.Size(o.Output)
+	if err == nil {
+		err = os.WriteFile(reportSize, []byte(strconv.FormatInt(size, 10)), 0o644)
+	}
+	if err != nil {
+		log.Error(err)
+		os.Exit(downloader.ExitWrite)
+	}
+}
@@ -4,11 +4,13 @@ import argparse
 
 parser = argparse.ArgumentParser()
 parser.add_argument('--uri', required=True, help='Model URI e.g. microsoft/phi-1_5')
+parser.add_argument('--tensor-parallel', type=int, default=1, help='Number of GPUs to split the model across')
 
 args = parser.parse_args()
 
 client = mii.serve(args.uri,
                    deployment_name="default",
+                   tensor_parallel=args.tensor_parallel,
                    enable_restful_api=True,
                    restful_api_port=8080,
                    restful_api_host="0.0.0.0")
@@ -0,0 +1,44 @@
+package aideployment
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/resources"
+)
+
+// ApplyInlineConfigs creates the ConfigMap holding the engineConfigFile of
+// inline models, with the same keys as an AIModelMap's ConfigMap so the
+// engines can project it in the same way. The ConfigMap is deleted when no
+// inline model has a config file.
+func ApplyInlineConfigs(ctx context.Context, c ctrlClient.Client, sd *v1alpha1.AIDeployment, engine v1alpha1.AIEngineName, models []aimodelmap.ResolvedModel) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            aimodelmap.InlineConfigMapName(sd.Name),
+			Namespace:       sd.Namespace,
+			OwnerReferences: resources.GenOwner(&sd.ObjectMeta),
+			Labels:          resources.GenDefaultLabels(sd.Name),
+			Annotations:     resources.GenDefaultAnnotation(sd.Name),
+		},
+	}
+
+	for _, m := range models {
+		if m.Variant == aimodelmap.InlineVariant && m.Spec.EngineConfigFile != "" {
+			aimodelmap.SetEngineConfigFileData(cm, engine, m.Variant, m.Spec.EngineConfigFile)
+		}
+	}
+
+	if len(cm.Data) == 0 {
+		return deleteOwned(ctx, c, sd, cm)
+	}
+
+	log.Debug("Applying inline configs ", cm.Namespace, ":", cm.Name)
+	_, err := resources.Apply(ctx, c, cm)
+	return err
+}
@@ -0,0 +1,58 @@
+package aideployment
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/modelcache"
+)
+
+// CheckModelCache sets the ModelsCached condition from the status of the
+// AIDeployment's ModelCache. It returns true once every model which can be
+// cached has been downloaded into it, until then the Deployment must be left
+// as it is so that pods don't start without their models.
+func CheckModelCache(ctx context.Context, c ctrlClient.Client, ai *v1alpha1.AIDeployment, models []aimodelmap.ResolvedModel) (bool, error) {
+	name := ai.Spec.ModelCacheRef.Name
+
+	cache := &v1alpha1.ModelCache{}
+	err := c.Get(ctx, ctrlClient.ObjectKey{Namespace: ai.Namespace, Name: name}, cache)
+	if apierrors.IsNotFound(err) {
+		SetCondition(ai, constants.ConditionModelsCached, metav1.ConditionFalse, constants.ReasonCacheNotFound,
+			fmt.Sprintf("ModelCache %s not found", name))
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, m := range models {
+		if !modelcache.Cacheable(m) {
+			continue
+		}
+
+		entry := modelcache.Find(cache, modelcache.Key(m))
+		if entry == nil || entry.Phase != v1alpha1.CachedModelPhaseReady {
+			reason := constants.ReasonDownloading
+			msg := fmt.Sprintf("waiting for %s to be downloaded into ModelCache %s", m.Spec.Uri, name)
+			if entry != nil && entry.Phase == v1alpha1.CachedModelPhaseFailed {
+				reason = constants.ReasonDownloadFailed
+				msg = fmt.Sprintf("downloading %s into ModelCache %s failed: %s", m.Spec.Uri, name, entry.Message)
+			}
+
+			SetCondition(ai, constants.ConditionModelsCached, metav1.ConditionFalse, reason, msg)
+			return false, nil
+		}
+	}
+
+	SetCondition(ai, constants.ConditionModelsCached, metav1.ConditionTrue, constants.ReasonCached,
+		fmt.Sprintf("models are in ModelCache %s", name))
+
+	return true, nil
+}
@@ -0,0 +1,323 @@
+package aideployment
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/resources"
+	"github.com/premAI-io/prem-operator/pkg/utils"
+)
+
+// MultiNodeEngine is implemented by engines which can split a replica across
+// a group of pods
+type MultiNodeEngine interface {
+	MLEngine
+	// MultiNode changes the pod template rendered by Deployment into the
+	// leader's and returns the template of the workers. The engine container
+	// of both has the number of pods in the group in constants.EnvGroupSize,
+	// the workers also have the leader's address in constants.EnvLeaderAddress.
+	MultiNode(leader *corev1.PodTemplateSpec, size int32) (*corev1.PodTemplateSpec, error)
+}
+
+// MultiNodeGroups is what a multi-node AIDeployment is rendered as. There is
+// a StatefulSet of leaders, one per replica, and a StatefulSet of workers for
+// each leader. The headless Service gives the leaders stable addresses.
+type MultiNodeGroups struct {
+	Headless *corev1.Service
+	Leaders  *appsv1.StatefulSet
+	Workers  []*appsv1.StatefulSet
+}
+
+func multiNodeServiceName(name string) string {
+	return name + "-group"
+}
+
+// RenderMultiNode generates the objects for a multi-node AIDeployment from the
+// Deployment that Render generated
+func RenderMultiNode(sd *v1alpha1.AIDeployment, mle MLEngine, deployment *appsv1.Deployment) (*MultiNodeGroups, error) {
+	mne, ok := mle.(MultiNodeEngine)
+	if !ok {
+		return nil, fmt.Errorf("engine %s can't split a replica across several pods", sd.Spec.Engine.Name)
+	}
+
+	size := sd.Spec.Deployment.MultiNode.Size
+	if size < 2 {
+		return nil, fmt.Errorf("a multi-node group must have at least 2 pods")
+	}
+
+	replicas := int32(1)
+	if sd.Spec.Deployment.Replicas != nil {
+		replicas = *sd.Spec.Deployment.Replicas
+	}
+
+	serviceName := multiNodeServiceName(sd.Name)
+	leaderTemplate := deployment.Spec.Template.DeepCopy()
+	setContainerEnv(leaderTemplate, corev1.EnvVar{Name: constants.EnvGroupSize, Value: strconv.Itoa(int(size))})
+
+	workerTemplate, err := mne.MultiNode(leaderTemplate, size)
+	if err != nil {
+		return nil, err
+	}
+
+	leaderLabels := utils.MergeMaps(deployment.Spec.Selector.MatchLabels, map[string]string{
+		constants.MultiNodeRoleLabel: constants.MultiNodeRoleLeader,
+	})
+	leaderTemplate.Labels = utils.MergeMaps(leaderTemplate.Labels, leaderLabels)
+
+	groups := &MultiNodeGroups{
+		Headless: &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            serviceName,
+				Namespace:       sd.Namespace,
+				OwnerReferences: resources.GenOwner(&sd.ObjectMeta),
+				Labels:          resources.GenDefaultLabels(sd.Name),
+			},
+			Spec: corev1.ServiceSpec{
+				ClusterIP: corev1.ClusterIPNone,
+				Selector:  leaderLabels,
+				// Workers must be able to join before the leader is ready
+				PublishNotReadyAddresses: true,
+			},
+		},
+		Leaders: &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            sd.Name,
+				Namespace:       sd.Namespace,
+				OwnerReferences: resources.GenOwner(&sd.ObjectMeta),
+				Labels:          resources.GenDefaultLabels(sd.Name),
+			},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas:            &replicas,
+				ServiceName:         serviceName,
+				PodManagementPolicy: appsv1.ParallelPodManagement,
+				Selector:            &metav1.LabelSelector{MatchLabels: leaderLabels},
+				Template:            *leaderTemplate,
+			},
+		},
+	}
+
+	workers := size - 1
+	for i := int32(0); i < replicas; i++ {
+		group := strconv.Itoa(int(i))
+		workerLabels := utils.MergeMaps(deployment.Spec.Selector.MatchLabels, map[string]string{
+			constants.MultiNodeRoleLabel:  constants.MultiNodeRoleWorker,
+			constants.MultiNodeGroupLabel: group,
+		})
+
+		template := workerTemplate.DeepCopy()
+		template.Labels = utils.MergeMaps(template.Labels, workerLabels)
+		setContainerEnv(template, corev1.EnvVar{
+			Name:  constants.EnvLeaderAddress,
+			Value: fmt.Sprintf("%s-%d.%s", sd.Name, i, serviceName),
+		})
+
+		groups.Workers = append(groups.Workers, &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            fmt.Sprintf("%s-worker-%d", sd.Name, i),
+				Namespace:       sd.Namespace,
+				OwnerReferences: resources.GenOwner(&sd.ObjectMeta),
+				Labels: utils.MergeMaps(resources.GenDefaultLabels(sd.Name), map[string]string{
+					constants.MultiNodeRoleLabel:  constants.MultiNodeRoleWorker,
+					constants.MultiNodeGroupLabel: group,
+				}),
+			},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas:            &workers,
+				ServiceName:         serviceName,
+				PodManagementPolicy: appsv1.ParallelPodManagement,
+				Selector:            &metav1.LabelSelector{MatchLabels: workerLabels},
+				Template:            *template,
+			},
+		})
+	}
+
+	return groups, nil
+}
+
+// setContainerEnv sets an env var in the engine container, replacing any
+// with the same name
+func setContainerEnv(template *corev1.PodTemplateSpec, env corev1.EnvVar) {
+	for i := range template.Spec.Containers {
+		c := &template.Spec.Containers[i]
+		if c.Name != constants.ContainerEngineName {
+			continue
+		}
+
+		for j := range c.Env {
+			if c.Env[j].Name == env.Name {
+				c.Env[j] = env
+				return
+			}
+		}
+		c.Env = append(c.Env, env)
+	}
+}
+
+// applyMultiNode applies the objects of a multi-node AIDeployment, removes
+// the Deployment and any worker groups left from a previous configuration and
+// sets the deployment conditions from the state of the groups. The leader pods
+// are read with pods, as the manager doesn't cache them.
+func applyMultiNode(ctx context.Context, c ctrlClient.Client, pods ctrlClient.Reader, sd *v1alpha1.AIDeployment, groups *MultiNodeGroups) ([]string, error) {
+	conflicts := []string{}
+	objs := []ctrlClient.Object{groups.Headless, groups.Leaders}
+	for _, w := range groups.Workers {
+		objs = append(objs, w)
+	}
+
+	for _, obj := range objs {
+		log.Debug("Applying ", obj.GetNamespace(), ":", obj.GetName())
+		if _, err := resources.Apply(ctx, c, obj); err != nil {
+			if !apierrors.IsConflict(err) {
+				SetCondition(sd, constants.ConditionDeploymentAvailable, metav1.ConditionFalse, constants.ReasonApplyFailed, err.Error())
+				return nil, err
+			}
+
+			conflicts = append(conflicts, fmt.Sprintf("%s: %v", obj.GetName(), err))
+			if err := c.Get(ctx, ctrlClient.ObjectKeyFromObject(obj), obj); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := deleteOwned(ctx, c, sd, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: sd.Name, Namespace: sd.Namespace}}); err != nil {
+		return nil, err
+	}
+
+	workers := &appsv1.StatefulSetList{}
+	if err := c.List(ctx, workers, ctrlClient.InNamespace(sd.Namespace), ctrlClient.MatchingLabels{
+		resources.DefaultLabel:       sd.Name,
+		constants.MultiNodeRoleLabel: constants.MultiNodeRoleWorker,
+	}); err != nil {
+		return nil, err
+	}
+	for i := range workers.Items {
+		group, err := strconv.Atoi(workers.Items[i].Labels[constants.MultiNodeGroupLabel])
+		if err == nil && group < len(groups.Workers) {
+			continue
+		}
+
+		if err := deleteOwned(ctx, c, sd, &workers.Items[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	leaders := &corev1.PodList{}
+	if err := pods.List(ctx, leaders, ctrlClient.InNamespace(sd.Namespace), ctrlClient.MatchingLabels(groups.Leaders.Spec.Selector.MatchLabels)); err != nil {
+		return nil, err
+	}
+
+	setMultiNodeConditions(sd, groups, leaders.Items)
+
+	return conflicts, nil
+}
+
+// deleteMultiNode removes the objects of a multi-node AIDeployment after it
+// has been changed to use a Deployment
+func deleteMultiNode(ctx context.Context, c ctrlClient.Client, sd *v1alpha1.AIDeployment) error {
+	sets := &appsv1.StatefulSetList{}
+	if err := c.List(ctx, sets, ctrlClient.InNamespace(sd.Namespace), ctrlClient.MatchingLabels(resources.GenDefaultLabels(sd.Name))); err != nil {
+		return err
+	}
+
+	for i := range sets.Items {
+		if err := deleteOwned(ctx, c, sd, &sets.Items[i]); err != nil {
+			return err
+		}
+	}
+
+	if len(sets.Items) == 0 {
+		return nil
+	}
+
+	return deleteOwned(ctx, c, sd, &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+		Name: multiNodeServiceName(sd.Name), Namespace: sd.Namespace,
+	}})
+}
+
+// deleteOwned deletes obj if it exists and is controlled by sd
+func deleteOwned(ctx context.Context, c ctrlClient.Client, sd *v1alpha1.AIDeployment, obj ctrlClient.Object) error {
+	if err := c.Get(ctx, ctrlClient.ObjectKeyFromObject(obj), obj); err != nil {
+		return ctrlClient.IgnoreNotFound(err)
+	}
+
+	if owner := metav1.GetControllerOf(obj); owner == nil || owner.UID != sd.UID {
+		return nil
+	}
+
+	log.Debug("Deleting ", obj.GetNamespace(), ":", obj.GetName())
+	return ctrlClient.IgnoreNotFound(c.Delete(ctx, obj))
+}
+
+// setMultiNodeConditions sets the DeploymentAvailable and Progressing
+// conditions. Group i is only counted as available when its leader, the pod
+// <name>-i of the leaders, and all of its workers are ready.
+func setMultiNodeConditions(sd *v1alpha1.AIDeployment, groups *MultiNodeGroups, leaders []corev1.Pod) {
+	replicas := statefulSetReplicas(groups.Leaders)
+
+	readyLeaders := map[string]bool{}
+	for i := range leaders {
+		readyLeaders[leaders[i].Name] = podReady(&leaders[i])
+	}
+
+	available := int32(0)
+	complete := statefulSetComplete(groups.Leaders)
+	for i, w := range groups.Workers {
+		leader := fmt.Sprintf("%s-%d", groups.Leaders.Name, i)
+		if readyLeaders[leader] && w.Status.ReadyReplicas == statefulSetReplicas(w) {
+			available++
+		}
+		complete = complete && statefulSetComplete(w)
+	}
+
+	msg := fmt.Sprintf("%d/%d groups available", available, replicas)
+
+	if available > 0 {
+		SetCondition(sd, constants.ConditionDeploymentAvailable, metav1.ConditionTrue, constants.ReasonAvailable, msg)
+	} else {
+		SetCondition(sd, constants.ConditionDeploymentAvailable, metav1.ConditionFalse, constants.ReasonUnavailable, msg)
+	}
+
+	if complete {
+		SetCondition(sd, constants.ConditionProgressing, metav1.ConditionFalse, constants.ReasonComplete, msg)
+	} else {
+		SetCondition(sd, constants.ConditionProgressing, metav1.ConditionTrue, constants.ReasonRollingOut, msg)
+	}
+}
+
+// statefulSetReplicas is the number of replicas s should have, which is 1
+// if it isn't set
+func statefulSetReplicas(s *appsv1.StatefulSet) int32 {
+	if s.Spec.Replicas != nil {
+		return *s.Spec.Replicas
+	}
+
+	return 1
+}
+
+func statefulSetComplete(s *appsv1.StatefulSet) bool {
+	replicas := statefulSetReplicas(s)
+
+	return s.Status.ObservedGeneration >= s.Generation &&
+		s.Status.UpdatedReplicas == replicas &&
+		s.Status.ReadyReplicas == replicas
+}
+
+func podReady(p *corev1.Pod) bool {
+	for _, c := range p.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,140 @@
+package aideployment_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aideployment"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/engines"
+)
+
+// applyOrCreate creates an object which is applied before it exists, which
+// the fake client can't do
+func applyOrCreate(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() == types.ApplyPatchType {
+		err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj.DeepCopyObject().(client.Object))
+		if apierrors.IsNotFound(err) {
+			return c.Create(ctx, obj)
+		}
+	}
+
+	return c.Patch(ctx, obj, patch, opts...)
+}
+
+var _ = Describe("Multi-node", func() {
+	var (
+		c   client.Client
+		ai  *a1.AIDeployment
+		mle aideployment.MLEngine
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(a1.AddToScheme(scheme)).To(Succeed())
+		c = fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithInterceptorFuncs(interceptor.Funcs{Patch: applyOrCreate}).
+			Build()
+
+		replicas := int32(2)
+		ai = &a1.AIDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "llm", Namespace: "default", UID: "llm-uid"},
+			Spec: a1.AIDeploymentSpec{
+				Engine: a1.AIEngine{Name: a1.AIEngineNameVLLM},
+				Deployment: a1.Deployment{
+					Replicas:  &replicas,
+					MultiNode: &a1.MultiNode{Size: 2},
+				},
+			},
+		}
+
+		def, ok := engines.Lookup(a1.AIEngineNameVLLM)
+		Expect(ok).To(BeTrue())
+
+		var err error
+		mle, err = def.Create(ai, []aimodelmap.ResolvedModel{{
+			Name:     "llm",
+			Variant:  aimodelmap.InlineVariant,
+			HostName: "llm-model",
+			Spec:     a1.AIModelSpec{Uri: "facebook/opt-125m"},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(aideployment.Reconcile(ai, context.Background(), c, c, mle)).To(Succeed())
+	})
+
+	setReady := func(name string, ready int32) {
+		s := &appsv1.StatefulSet{}
+		Expect(c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: name}, s)).To(Succeed())
+		s.Status.ReadyReplicas = ready
+		Expect(c.Status().Update(context.Background(), s)).To(Succeed())
+	}
+
+	addLeader := func(i int, ready corev1.ConditionStatus) {
+		leaders := &appsv1.StatefulSet{}
+		Expect(c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "llm"}, leaders)).To(Succeed())
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("llm-%d", i),
+				Namespace: "default",
+				Labels:    leaders.Spec.Selector.MatchLabels,
+			},
+		}
+		Expect(c.Create(context.Background(), pod)).To(Succeed())
+
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}}
+		Expect(c.Status().Update(context.Background(), pod)).To(Succeed())
+	}
+
+	DescribeTable("counts the groups whose leader and workers are ready",
+		func(leaders []corev1.ConditionStatus, workers []int32, status metav1.ConditionStatus, msg string) {
+			readyLeaders := int32(0)
+			for i, ready := range leaders {
+				addLeader(i, ready)
+				if ready == corev1.ConditionTrue {
+					readyLeaders++
+				}
+			}
+			setReady("llm", readyLeaders)
+			for i, ready := range workers {
+				setReady(fmt.Sprintf("llm-worker-%d", i), ready)
+			}
+
+			Expect(aideployment.Reconcile(ai, context.Background(), c, c, mle)).To(Succeed())
+
+			cond := meta.FindStatusCondition(ai.Status.Conditions, constants.ConditionDeploymentAvailable)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal(status))
+			Expect(cond.Message).To(Equal(msg))
+		},
+		Entry("no pods", nil, nil, metav1.ConditionFalse, "0/2 groups available"),
+		Entry("the leader of one group and the workers of the other",
+			[]corev1.ConditionStatus{corev1.ConditionTrue, corev1.ConditionFalse}, []int32{0, 1},
+			metav1.ConditionFalse, "0/2 groups available"),
+		Entry("one whole group",
+			[]corev1.ConditionStatus{corev1.ConditionTrue, corev1.ConditionFalse}, []int32{1, 1},
+			metav1.ConditionTrue, "1/2 groups available"),
+		Entry("every group",
+			[]corev1.ConditionStatus{corev1.ConditionTrue, corev1.ConditionTrue}, []int32{1, 1},
+			metav1.ConditionTrue, "2/2 groups available"),
+	)
+})
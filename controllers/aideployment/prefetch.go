@@ -0,0 +1,33 @@
+package aideployment
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/modelcache"
+)
+
+// SetPrefetched marks the models which an AIModelPrefetch has downloaded
+// onto at least one node. The engine's pods then prefer those nodes and load
+// the models from the node they are scheduled onto if it has them, otherwise
+// the models are downloaded as usual.
+func SetPrefetched(ctx context.Context, c ctrlClient.Client, models []aimodelmap.ResolvedModel) error {
+	for i := range models {
+		m := &models[i]
+		if !modelcache.Cacheable(*m) {
+			continue
+		}
+
+		nodes := &corev1.NodeList{}
+		if err := c.List(ctx, nodes, ctrlClient.HasLabels{modelcache.NodeLabel(*m)}, ctrlClient.Limit(1)); err != nil {
+			return err
+		}
+
+		m.Prefetched = len(nodes.Items) > 0
+	}
+
+	return nil
+}
@@ -3,18 +3,19 @@ package aideployment
 import (
 	"context"
 	"fmt"
+	"strings"
+
 	"github.com/premAI-io/prem-operator/controllers/constants"
 	log "github.com/sirupsen/logrus"
-	networkv1 "k8s.io/api/networking/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/premAI-io/prem-operator/api/v1alpha1"
 	"github.com/premAI-io/prem-operator/controllers/resources"
 	appsv1 "k8s.io/api/apps/v1"
-	v1 "k8s.io/api/core/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 type MLEngine interface {
@@ -22,13 +23,49 @@ type MLEngine interface {
 	Deployment(owner metav1.Object) (*appsv1.Deployment, error)
 }
 
-func Reconcile(sd v1alpha1.AIDeployment, ctx context.Context, c ctrlClient.Client, mle MLEngine) (int, error) {
-	requeue := 0
+// MultiPortEngine is implemented by engines which serve on other ports as
+// well as Port(), e.g. gRPC or metrics. The ports should be named and include
+// Port(), the Service exposes all of them.
+type MultiPortEngine interface {
+	MLEngine
+	ServicePorts() []corev1.ServicePort
+}
+
+// ServicePorts returns the ports of the engine's Service
+func ServicePorts(mle MLEngine) []corev1.ServicePort {
+	if mpe, ok := mle.(MultiPortEngine); ok {
+		return mpe.ServicePorts()
+	}
+
+	return []corev1.ServicePort{
+		{
+			Port: mle.Port(), TargetPort: intstr.FromInt(int(mle.Port())),
+		},
+	}
+}
+
+// EndpointPort is the Service port an endpoint routes to, the engine's main
+// port unless the endpoint sets one. It errors if the Service doesn't have
+// the port.
+func EndpointPort(e v1alpha1.Endpoint, mle MLEngine) (int32, error) {
+	if e.Port == 0 {
+		return mle.Port(), nil
+	}
 
-	// Generate a Deployment from the Engine
+	for _, p := range ServicePorts(mle) {
+		if p.Port == e.Port {
+			return e.Port, nil
+		}
+	}
+
+	return 0, fmt.Errorf("endpoint %s: engine doesn't serve on port %d", e.Domain, e.Port)
+}
+
+// Render generates the Deployment for an AIDeployment from its engine
+func Render(sd *v1alpha1.AIDeployment, mle MLEngine) (*appsv1.Deployment, error) {
 	deployment, err := mle.Deployment(&sd.ObjectMeta)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	container := findContainerEngine(deployment)
@@ -37,42 +74,48 @@ func Reconcile(sd v1alpha1.AIDeployment, ctx context.Context, c ctrlClient.Clien
 	}
 
 	// Add generic Scheduling properties
-	err = AddSchedulingProperties(deployment, sd.Spec)
+	if err := AddSchedulingProperties(deployment, sd.Spec); err != nil {
+		return nil, err
+	}
+
+	return deployment, nil
+}
+
+// Reconcile applies the objects rendered for sd and sets its conditions from
+// their state. Pods which the manager doesn't cache, such as the leaders of a
+// multi-node AIDeployment, are read with pods.
+func Reconcile(sd *v1alpha1.AIDeployment, ctx context.Context, c ctrlClient.Client, pods ctrlClient.Reader, mle MLEngine) error {
+	deployment, err := Render(sd, mle)
 	if err != nil {
-		return 0, err
-	}
-
-	d := &appsv1.Deployment{}
-	// try to find if a deployment already exists
-	if err := c.Get(ctx, types.NamespacedName{Namespace: sd.GetNamespace(), Name: sd.GetName()}, d); err != nil {
-		if apierrors.IsNotFound(err) { // Create a deployment
-			log.Info("Creating deployment", deployment.Namespace, ":", deployment.Name)
-			d = deployment.DeepCopy()
-			if err := c.Create(ctx, d); err != nil {
-				return 0, err
-			}
-		} else {
-			return 0, err
+		SetCondition(sd, constants.ConditionEngineConfigured, metav1.ConditionFalse, constants.ReasonInvalidConfig, err.Error())
+		return err
+	}
+
+	var conflicts []string
+	selector := deployment.Spec.Template.Labels
+
+	if sd.Spec.Deployment.MultiNode != nil {
+		groups, err := RenderMultiNode(sd, mle, deployment)
+		if err != nil {
+			SetCondition(sd, constants.ConditionEngineConfigured, metav1.ConditionFalse, constants.ReasonInvalidConfig, err.Error())
+			return err
 		}
-	} else { // Update a deployment
-		deployment.ResourceVersion = d.ResourceVersion
-		d = deployment.DeepCopy()
-
-		log.Debug("Updating deployment ", deployment.Namespace, ":", deployment.Name)
-		if err := c.Update(ctx, d); err != nil {
-			if apierrors.IsConflict(err) {
-				log.Info("Deployment changed during update, requeueing")
-				return 1, nil
-			}
-			return 0, err
+		SetCondition(sd, constants.ConditionEngineConfigured, metav1.ConditionTrue, constants.ReasonConfigured, "")
+
+		if conflicts, err = applyMultiNode(ctx, c, pods, sd, groups); err != nil {
+			return err
 		}
-	}
+		selector = groups.Leaders.Spec.Template.Labels
+	} else {
+		SetCondition(sd, constants.ConditionEngineConfigured, metav1.ConditionTrue, constants.ReasonConfigured, "")
 
-	re, err := UpdateAIDeploymentStatus(ctx, c, &sd, d, "")
-	if err != nil {
-		return 0, err
+		if conflicts, err = applyDeployment(ctx, c, sd, deployment); err != nil {
+			return err
+		}
+		if err := deleteMultiNode(ctx, c, sd); err != nil {
+			return err
+		}
 	}
-	requeue = re
 
 	annotations := resources.GenDefaultAnnotation(sd.Name)
 	for k, v := range sd.Spec.Service.Annotations {
@@ -83,42 +126,42 @@ func Reconcile(sd v1alpha1.AIDeployment, ctx context.Context, c ctrlClient.Clien
 		&sd.ObjectMeta,
 		deployment.Name,
 		deployment.Namespace,
-		deployment.Spec.Template.Labels,
+		selector,
 		sd.Spec.Service.Labels,
 		annotations,
-		mle.Port(),
+		ServicePorts(mle),
 	)
 
-	svcK := &v1.Service{}
-	// try to find if a svc already exists
-	if err := c.Get(ctx, types.NamespacedName{Namespace: deployment.Namespace, Name: deployment.Name}, svcK); err != nil {
-		if apierrors.IsNotFound(err) { // Create a deployment
-			log.Debug("Creating service ", svc.Namespace, ":", svc.Name)
-			svcK = svc.DeepCopy()
-			if err := c.Create(ctx, svcK); err != nil {
-				return 0, err
-			}
-		} else {
-			return 0, err
+	log.Debug("Applying service ", svc.Namespace, ":", svc.Name)
+	if _, err := resources.Apply(ctx, c, svc); err != nil {
+		if !apierrors.IsConflict(err) {
+			SetCondition(sd, constants.ConditionServiceReady, metav1.ConditionFalse, constants.ReasonApplyFailed, err.Error())
+			return err
 		}
-	} else { // Update a deployment
-		svc.ResourceVersion = svcK.ResourceVersion
-		svcK = svc.DeepCopy()
 
-		log.Debug("Updating service ", svc.Namespace, ":", svc.Name)
-		if err := c.Update(ctx, svcK); err != nil {
-			return 0, err
-		}
+		conflicts = append(conflicts, fmt.Sprintf("service: %v", err))
+	} else {
+		SetCondition(sd, constants.ConditionServiceReady, metav1.ConditionTrue, constants.ReasonSynced, "")
 	}
 
 	if len(sd.Spec.Endpoint) == 0 {
 		log.Debug("No endpoint specified, skipping ingress creation")
-		return 0, nil
+		SetCondition(sd, constants.ConditionIngressReady, metav1.ConditionTrue, constants.ReasonNoEndpoints, "no endpoints specified")
+		setAppliedCondition(sd, conflicts)
+		return nil
 	}
 
 	domains := []string{}
+	routes := []resources.IngressRoute{}
 	for _, e := range sd.Spec.Endpoint {
+		port, err := EndpointPort(e, mle)
+		if err != nil {
+			SetCondition(sd, constants.ConditionIngressReady, metav1.ConditionFalse, constants.ReasonInvalidConfig, err.Error())
+			return err
+		}
+
 		domains = append(domains, e.Domain)
+		routes = append(routes, resources.IngressRoute{Host: e.Domain, Port: port})
 	}
 
 	tls := false
@@ -134,74 +177,71 @@ func Reconcile(sd v1alpha1.AIDeployment, ctx context.Context, c ctrlClient.Clien
 		&sd.ObjectMeta,
 		deployment.Name,
 		deployment.Namespace,
-		domains,
+		routes,
 		deployment.Name,
-		int(mle.Port()),
 		sd.Spec.Ingress.Labels,
 		annotations,
 		tls,
 	)
 
-	ingressK := &networkv1.Ingress{}
-	// try to find if an ingress already exists
-	if err := c.Get(ctx, types.NamespacedName{Namespace: deployment.Namespace, Name: deployment.Name}, ingressK); err != nil {
-		if apierrors.IsNotFound(err) {
-			log.Debug("Creating ingress ", ingress.Namespace, ":", ingress.Name)
-			ingressK = ingress.DeepCopy()
-			if err := c.Create(ctx, ingressK); err != nil {
-				return 0, err
-			}
-		} else {
-			return 0, err
-		}
-	} else { // Update a deployment
-		ingress.ResourceVersion = ingressK.ResourceVersion
-		ingressK = ingress.DeepCopy()
-		log.Debug("Updating ingress ", ingress.Namespace, ":", ingress.Name)
-		if err := c.Update(ctx, ingressK); err != nil {
-			return 0, err
+	log.Debug("Applying ingress ", ingress.Namespace, ":", ingress.Name)
+	if _, err := resources.Apply(ctx, c, ingress); err != nil {
+		if !apierrors.IsConflict(err) {
+			SetCondition(sd, constants.ConditionIngressReady, metav1.ConditionFalse, constants.ReasonApplyFailed, err.Error())
+			return err
 		}
+
+		conflicts = append(conflicts, fmt.Sprintf("ingress: %v", err))
+	} else {
+		SetCondition(sd, constants.ConditionIngressReady, metav1.ConditionTrue, constants.ReasonSynced, strings.Join(domains, ", "))
 	}
 
+	setAppliedCondition(sd, conflicts)
+
 	log.Debug(
 		"Reconcile completed: ", sd.Name, " in namespace: ", sd.Namespace,
 	)
 
-	return requeue, nil
+	return nil
 }
 
-// UpdateAIDeploymentStatus updates the status of the AI deployment
-func UpdateAIDeploymentStatus(
-	ctx context.Context,
-	c ctrlClient.Client,
-	aiDeployment *v1alpha1.AIDeployment,
-	deployment *appsv1.Deployment,
-	errMsg string,
-) (int, error) {
-	aiDep := aiDeployment.DeepCopy()
-
-	if errMsg != "" {
-		aiDep.Status.Status = constants.Failed
-		aiDep.Status.ErrMsg = errMsg
-		if err := c.Status().Update(ctx, aiDep); err != nil {
-			return 0, fmt.Errorf("failed to update AI deployment status: %w", err)
+// applyDeployment applies the Deployment of a single node AIDeployment and
+// sets the deployment conditions from its status
+func applyDeployment(ctx context.Context, c ctrlClient.Client, sd *v1alpha1.AIDeployment, deployment *appsv1.Deployment) ([]string, error) {
+	// Objects which another field manager has taken ownership of
+	conflicts := []string{}
+
+	d := deployment.DeepCopy()
+	log.Debug("Applying deployment ", deployment.Namespace, ":", deployment.Name)
+	if _, err := resources.Apply(ctx, c, d); err != nil {
+		if !apierrors.IsConflict(err) {
+			SetCondition(sd, constants.ConditionDeploymentAvailable, metav1.ConditionFalse, constants.ReasonApplyFailed, err.Error())
+			return nil, err
 		}
 
-		return 0, nil
+		conflicts = append(conflicts, fmt.Sprintf("deployment: %v", err))
+		d = &appsv1.Deployment{}
+		if err := c.Get(ctx, ctrlClient.ObjectKeyFromObject(deployment), d); err != nil {
+			return nil, err
+		}
 	}
 
 	// The status of the Deployment might not be immediately available after the
-	// Deployment resource is created or updated, requeue to check the deployment
-	// status again after 3 seconds
-	requeue := 3
-	aiDep.Status.Status = constants.NotReady
-	if deployment.Status.AvailableReplicas > 0 {
-		aiDep.Status.Status = constants.Ready
-		requeue = 0
-	}
+	// Deployment resource is created or updated, we watch the Deployment so will
+	// be called again when it changes
+	setDeploymentConditions(sd, d)
+
+	return conflicts, nil
+}
 
-	if err := c.Status().Update(ctx, aiDep); err != nil {
-		return requeue, fmt.Errorf("failed to update AI deployment status: %w", err)
+// setAppliedCondition reports objects that could not be applied because
+// another field manager owns some of the fields the operator sets. These are
+// not retried until either the AIDeployment or the object changes.
+func setAppliedCondition(sd *v1alpha1.AIDeployment, conflicts []string) {
+	if len(conflicts) > 0 {
+		SetCondition(sd, constants.ConditionResourcesApplied, metav1.ConditionFalse, constants.ReasonApplyConflict, strings.Join(conflicts, "; "))
+		return
 	}
-	return requeue, nil
+
+	SetCondition(sd, constants.ConditionResourcesApplied, metav1.ConditionTrue, constants.ReasonSynced, "")
 }
@@ -35,7 +35,8 @@ func addTopologySpread(tmpl *v1.PodTemplateSpec) {
 	})
 }
 
-func neededGPUs(deploy a1.Deployment) (resource.Quantity, error) {
+// NeededGPUs is the number of Nvidia GPUs each replica requests
+func NeededGPUs(deploy a1.Deployment) (resource.Quantity, error) {
 	gpus := resource.MustParse("0")
 
 	if deploy.Accelerator == nil {
@@ -80,7 +81,7 @@ func AddSchedulingProperties(appDeployment *appsv1.Deployment, AIDeployment a1.A
 	pod := &appDeployment.Spec.Template.Spec
 	pod.NodeSelector = utils.MergeMaps(pod.NodeSelector, AIDeployment.Deployment.NodeSelector)
 
-	gpus, err := neededGPUs(AIDeployment.Deployment)
+	gpus, err := NeededGPUs(AIDeployment.Deployment)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,112 @@
+package aideployment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var conditionTypes = []string{
+	constants.ConditionModelsResolved,
+	constants.ConditionEngineConfigured,
+	constants.ConditionDeploymentAvailable,
+	constants.ConditionServiceReady,
+	constants.ConditionIngressReady,
+	constants.ConditionProgressing,
+	constants.ConditionResourcesApplied,
+}
+
+// InitConditions adds any missing conditions with an unknown status so that
+// clients can see what is still to be done
+func InitConditions(ai *v1alpha1.AIDeployment) {
+	for _, t := range conditionTypes {
+		if meta.FindStatusCondition(ai.Status.Conditions, t) != nil {
+			continue
+		}
+
+		SetCondition(ai, t, metav1.ConditionUnknown, constants.ReasonReconciling, "")
+	}
+}
+
+// SetCondition sets a condition on the AIDeployment status, the transition
+// time is only changed if the status changes
+func SetCondition(ai *v1alpha1.AIDeployment, condType string, status metav1.ConditionStatus, reason, msg string) {
+	meta.SetStatusCondition(&ai.Status.Conditions, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		ObservedGeneration: ai.Generation,
+		Reason:             reason,
+		Message:            msg,
+	})
+}
+
+// SetFailed marks the AIDeployment as no longer progressing because of err
+func SetFailed(ai *v1alpha1.AIDeployment, err error) {
+	SetCondition(ai, constants.ConditionProgressing, metav1.ConditionFalse, constants.ReasonFailed, err.Error())
+}
+
+// setDeploymentConditions copies the state of the Deployment into the
+// DeploymentAvailable and Progressing conditions
+func setDeploymentConditions(ai *v1alpha1.AIDeployment, d *appsv1.Deployment) {
+	available := d.Status.AvailableReplicas > 0
+	msg := fmt.Sprintf("%d/%d replicas available", d.Status.AvailableReplicas, d.Status.Replicas)
+
+	if available {
+		SetCondition(ai, constants.ConditionDeploymentAvailable, metav1.ConditionTrue, constants.ReasonAvailable, msg)
+	} else {
+		SetCondition(ai, constants.ConditionDeploymentAvailable, metav1.ConditionFalse, constants.ReasonUnavailable, msg)
+	}
+
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Status == v1.ConditionFalse {
+			SetCondition(ai, constants.ConditionProgressing, metav1.ConditionFalse, c.Reason, c.Message)
+			return
+		}
+	}
+
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+
+	complete := d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.UpdatedReplicas == replicas &&
+		d.Status.Replicas == replicas &&
+		d.Status.AvailableReplicas == replicas
+
+	if complete {
+		SetCondition(ai, constants.ConditionProgressing, metav1.ConditionFalse, constants.ReasonComplete, msg)
+	} else {
+		SetCondition(ai, constants.ConditionProgressing, metav1.ConditionTrue, constants.ReasonRollingOut,
+			fmt.Sprintf("%d/%d replicas updated, %s", d.Status.UpdatedReplicas, replicas, msg))
+	}
+}
+
+// UpdateAIDeploymentStatus writes the status of the AI deployment if it
+// differs from old
+func UpdateAIDeploymentStatus(
+	ctx context.Context,
+	c ctrlClient.Client,
+	aiDeployment *v1alpha1.AIDeployment,
+	old *v1alpha1.AIDeploymentStatus,
+) error {
+	aiDeployment.Status.ObservedGeneration = aiDeployment.Generation
+
+	if old != nil && equality.Semantic.DeepEqual(old, &aiDeployment.Status) {
+		return nil
+	}
+
+	if err := c.Status().Update(ctx, aiDeployment); err != nil {
+		return fmt.Errorf("failed to update AI deployment status: %w", err)
+	}
+
+	return nil
+}
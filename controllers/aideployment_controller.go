@@ -19,33 +19,54 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"time"
+	"slices"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkv1 "k8s.io/api/networking/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/premAI-io/prem-operator/api/v1alpha1"
 	"github.com/premAI-io/prem-operator/controllers/aideployment"
 	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
 	"github.com/premAI-io/prem-operator/controllers/engines"
+	"github.com/premAI-io/prem-operator/controllers/modelcache"
 )
 
 // AIDeploymentReconciler reconciles a AIDeployment object
 type AIDeploymentReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// APIReader reads the leader pods of multi-node AIDeployments, which the
+	// manager doesn't cache
+	APIReader client.Reader
 }
 
 //+kubebuilder:rbac:groups=premlabs.io,resources=aideployments,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=premlabs.io,resources=aideployments/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=premlabs.io,resources=aideployments/finalizers,verbs=update
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups="",resources=services,verbs=create;get;list;update;watch
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=premlabs.io,resources=aimodelmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=premlabs.io,resources=aienginetemplates,verbs=get;list;watch
+//+kubebuilder:rbac:groups=premlabs.io,resources=modelcaches,verbs=get;list;watch
+//+kubebuilder:rbac:groups=premlabs.io,resources=aimodelprefetches,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -68,64 +89,212 @@ func (r *AIDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, err
 	}
 
-	var (
-		mlEngine aideployment.MLEngine
-		err      error
-	)
+	status := ent.Status.DeepCopy()
+	aideployment.InitConditions(&ent)
 
-	models, err := aimodelmap.Resolve(&ent, ctx, r.Client)
+	engine, err := engines.Get(ctx, r.Client, &ent)
 	if err != nil {
-		_, err1 := aideployment.UpdateAIDeploymentStatus(
-			ctx, r.Client, &ent, nil, err.Error(),
+		aideployment.SetCondition(
+			&ent, constants.ConditionEngineConfigured, metav1.ConditionFalse, constants.ReasonInvalidConfig, err.Error(),
 		)
 
-		return ctrl.Result{}, fmt.Errorf("%w: %v", err, err1)
+		return ctrl.Result{}, r.fail(ctx, &ent, status, err)
 	}
 
-	switch ent.Spec.Engine.Name {
-	case v1alpha1.AIEngineNameTriton:
-		mlEngine = engines.NewTriton(&ent, models)
-	case v1alpha1.AIEngineNameLocalai:
-		mlEngine = engines.NewLocalAI(&ent, models)
-	case v1alpha1.AIEngineNameVLLM:
-		mlEngine, err = engines.NewVllmAi(&ent, models)
-	case v1alpha1.AIEngineNameGeneric:
-		mlEngine = engines.NewGeneric(&ent)
-	case v1alpha1.AIEngineNameDeepSpeedMii:
-		mlEngine, err = engines.NewDeepSpeedMii(&ent, models)
-	default:
-		err = fmt.Errorf("unknown engine %s", ent.Spec.Engine.Name)
-	}
+	models, err := aimodelmap.Resolve(&ent, engine.ModelMapKey, ctx, r.Client)
 	if err != nil {
-		_, err1 := aideployment.UpdateAIDeploymentStatus(
-			ctx, r.Client, &ent, nil, err.Error(),
+		aideployment.SetCondition(
+			&ent, constants.ConditionModelsResolved, metav1.ConditionFalse, constants.ReasonResolutionFailed, err.Error(),
 		)
 
-		return ctrl.Result{}, fmt.Errorf("%w: %v", err, err1)
+		return ctrl.Result{}, r.fail(ctx, &ent, status, err)
 	}
 
-	requeue, err := aideployment.Reconcile(ent, ctx, r.Client, mlEngine)
-	if requeue > 0 {
-		return ctrl.Result{RequeueAfter: time.Second * time.Duration(requeue)}, err
+	aideployment.SetCondition(
+		&ent, constants.ConditionModelsResolved, metav1.ConditionTrue, constants.ReasonResolved,
+		fmt.Sprintf("%d models resolved", len(models)),
+	)
+
+	if engine.ModelPrefetch {
+		if err := aideployment.SetPrefetched(ctx, r.Client, models); err != nil {
+			return ctrl.Result{}, r.fail(ctx, &ent, status, err)
+		}
 	}
+
+	mlEngine, err := engine.Create(&ent, models)
 	if err != nil {
-		_, err1 := aideployment.UpdateAIDeploymentStatus(
-			ctx,
-			r.Client,
-			&ent,
-			nil,
-			fmt.Sprintf("Reconciliation error: %v", err),
+		aideployment.SetCondition(
+			&ent, constants.ConditionEngineConfigured, metav1.ConditionFalse, constants.ReasonInvalidConfig, err.Error(),
 		)
 
-		return ctrl.Result{}, fmt.Errorf("%w: %v", err, err1)
+		return ctrl.Result{}, r.fail(ctx, &ent, status, err)
 	}
 
-	return ctrl.Result{}, nil
+	if ent.Spec.ModelCacheRef != nil {
+		cached, err := aideployment.CheckModelCache(ctx, r.Client, &ent, models)
+		if err != nil {
+			return ctrl.Result{}, r.fail(ctx, &ent, status, err)
+		}
+
+		// The ModelCache is watched, so this is reconciled again when it
+		// changes
+		if !cached {
+			return ctrl.Result{}, aideployment.UpdateAIDeploymentStatus(ctx, r.Client, &ent, status)
+		}
+	}
+
+	if err := aideployment.ApplyInlineConfigs(ctx, r.Client, &ent, engine.Name, models); err != nil {
+		aideployment.SetCondition(
+			&ent, constants.ConditionEngineConfigured, metav1.ConditionFalse, constants.ReasonApplyFailed, err.Error(),
+		)
+
+		return ctrl.Result{}, r.fail(ctx, &ent, status, err)
+	}
+
+	if err := aideployment.Reconcile(&ent, ctx, r.Client, r.APIReader, mlEngine); err != nil {
+		return ctrl.Result{}, r.fail(ctx, &ent, status, fmt.Errorf("Reconciliation error: %w", err))
+	}
+
+	return ctrl.Result{}, aideployment.UpdateAIDeploymentStatus(ctx, r.Client, &ent, status)
+}
+
+// fail records err in the AIDeployment status and returns it along with any
+// error from updating the status
+func (r *AIDeploymentReconciler) fail(ctx context.Context, ent *v1alpha1.AIDeployment, old *v1alpha1.AIDeploymentStatus, err error) error {
+	aideployment.SetFailed(ent, err)
+
+	if err1 := aideployment.UpdateAIDeploymentStatus(ctx, r.Client, ent, old); err1 != nil {
+		return fmt.Errorf("%w: %v", err, err1)
+	}
+
+	return err
 }
 
-// SetupWithManager sets up the controller with the Manager.
+// SetupWithManager sets up the controller with the Manager. The objects
+// generated from an AIDeployment are watched so that the status is updated
+// when they become ready and so that changes to them are reverted. Changes to
+// AIModelMaps and AIEngineTemplates cause the AIDeployments referencing them
+// to be reconciled, as do changes to the status of ModelCaches and
+// AIModelPrefetches. The field
+// indexes registered here are also used by the ModelCacheReconciler.
 func (r *AIDeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&v1alpha1.AIDeployment{},
+		aimodelmap.ModelMapRefIndexKey,
+		aimodelmap.IndexModelMapRefs,
+	); err != nil {
+		return err
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&v1alpha1.AIDeployment{},
+		engines.EngineNameIndexKey,
+		engines.IndexEngineName,
+	); err != nil {
+		return err
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&v1alpha1.AIDeployment{},
+		modelcache.RefIndexKey,
+		modelcache.IndexModelCacheRef,
+	); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.AIDeployment{}).
+		For(&v1alpha1.AIDeployment{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&networkv1.Ingress{}).
+		Watches(
+			&v1alpha1.AIModelMap{},
+			handler.EnqueueRequestsFromMapFunc(r.findDeploymentsForModelMap),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(
+			&v1alpha1.AIEngineTemplate{},
+			handler.EnqueueRequestsFromMapFunc(r.findDeploymentsForEngineTemplate),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(
+			&v1alpha1.ModelCache{},
+			handler.EnqueueRequestsFromMapFunc(r.findDeploymentsForModelCache),
+		).
+		Watches(
+			&v1alpha1.AIModelPrefetch{},
+			handler.EnqueueRequestsFromMapFunc(r.findDeploymentsForPrefetch),
+		).
 		Complete(r)
 }
+
+// findDeploymentsForModelMap lists the AIDeployments which reference an AIModelMap
+func (r *AIDeploymentReconciler) findDeploymentsForModelMap(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.findDeployments(ctx, obj, client.MatchingFields{
+		aimodelmap.ModelMapRefIndexKey: aimodelmap.RefIndexValue(obj.GetNamespace(), obj.GetName()),
+	})
+}
+
+// findDeploymentsForEngineTemplate lists the AIDeployments which use an AIEngineTemplate
+func (r *AIDeploymentReconciler) findDeploymentsForEngineTemplate(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.findDeployments(ctx, obj, client.MatchingFields{
+		engines.EngineNameIndexKey: obj.GetName(),
+	})
+}
+
+// findDeploymentsForModelCache lists the AIDeployments which use a ModelCache
+func (r *AIDeploymentReconciler) findDeploymentsForModelCache(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.findDeployments(ctx, obj, client.InNamespace(obj.GetNamespace()), client.MatchingFields{
+		modelcache.RefIndexKey: obj.GetName(),
+	})
+}
+
+// findDeploymentsForPrefetch lists the AIDeployments which reference the
+// AIModelMaps an AIModelPrefetch downloads variants of
+func (r *AIDeploymentReconciler) findDeploymentsForPrefetch(ctx context.Context, obj client.Object) []reconcile.Request {
+	p, ok := obj.(*v1alpha1.AIModelPrefetch)
+	if !ok {
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, ref := range p.Spec.Models {
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = p.Spec.Namespace
+		}
+
+		for _, req := range r.findDeployments(ctx, obj, client.MatchingFields{
+			aimodelmap.ModelMapRefIndexKey: aimodelmap.RefIndexValue(namespace, ref.Name),
+		}) {
+			if !slices.Contains(requests, req) {
+				requests = append(requests, req)
+			}
+		}
+	}
+
+	return requests
+}
+
+func (r *AIDeploymentReconciler) findDeployments(ctx context.Context, obj client.Object, opts ...client.ListOption) []reconcile.Request {
+	deployments := &v1alpha1.AIDeploymentList{}
+	if err := r.List(ctx, deployments, opts...); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list AIDeployments referencing object",
+			"kind", obj.GetObjectKind().GroupVersionKind().Kind, "name", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(deployments.Items))
+	for _, d := range deployments.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: d.Namespace, Name: d.Name},
+		})
+	}
+
+	return requests
+}
@@ -0,0 +1,46 @@
+package aimodelmap
+
+import (
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+)
+
+// ModelMapRefIndexKey is the field index of AIDeployments by the AIModelMaps
+// they reference. The values are formatted by RefIndexValue.
+const ModelMapRefIndexKey = ".spec.models.modelMapRef"
+
+// RefIndexValue formats the value of ModelMapRefIndexKey for an AIModelMap
+func RefIndexValue(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// IndexModelMapRefs extracts the AIModelMaps referenced by an AIDeployment,
+// it is meant to be passed to a FieldIndexer
+func IndexModelMapRefs(obj ctrlClient.Object) []string {
+	d, ok := obj.(*a1.AIDeployment)
+	if !ok {
+		return nil
+	}
+
+	refs := []string{}
+	seen := map[string]bool{}
+	for _, m := range d.Spec.Models {
+		if m.ModelMapRef == nil || m.ModelMapRef.Name == "" {
+			continue
+		}
+
+		namespace := m.ModelMapRef.Namespace
+		if namespace == "" {
+			namespace = d.Namespace
+		}
+
+		ref := RefIndexValue(namespace, m.ModelMapRef.Name)
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs
+}
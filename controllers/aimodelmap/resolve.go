@@ -15,14 +15,18 @@ type ResolvedModel struct {
 	Variant  string
 	HostName string
 	Spec     a1.AIModelSpec
+	// Prefetched is set if an AIModelPrefetch has downloaded the model onto
+	// some nodes, which the engine then prefers
+	Prefetched bool
 }
 
-// Resolve resolves the models in the deployment
-func Resolve(d *a1.AIDeployment, ctx context.Context, c ctrlClient.Client) ([]ResolvedModel, error) {
+// Resolve resolves the models in the deployment. The modelMapKey is where the
+// engine's variants are found in an AIModelMap, see AIModelMapSpec.Variants.
+func Resolve(d *a1.AIDeployment, modelMapKey string, ctx context.Context, c ctrlClient.Client) ([]ResolvedModel, error) {
 	ms := make([]ResolvedModel, 0, len(d.Spec.Models))
 
 	for _, m := range d.Spec.Models {
-		rm, err := resolveOne(&m, d, ctx, c)
+		rm, err := ResolveOne(&m, d, modelMapKey, ctx, c)
 		if err != nil {
 			return nil, err
 		}
@@ -56,6 +60,15 @@ func mergeModelSpecs(primary *a1.AIModelSpec, secondary *a1.AIModelSpec) *a1.AIM
 		result.Uri = secondary.Uri
 	}
 
+	// The checksum is only for the file it was given with
+	if result.Sha256 == "" && result.Uri == secondary.Uri {
+		result.Sha256 = secondary.Sha256
+	}
+
+	if result.CredentialsSecretRef == nil && secondary.CredentialsSecretRef != nil {
+		result.CredentialsSecretRef = secondary.CredentialsSecretRef.DeepCopy()
+	}
+
 	if result.DataType == "" {
 		result.DataType = secondary.DataType
 	}
@@ -68,14 +81,32 @@ func mergeModelSpecs(primary *a1.AIModelSpec, secondary *a1.AIModelSpec) *a1.AIM
 		result.EngineConfigFile = secondary.EngineConfigFile
 	}
 
+	if result.ContextSize == 0 {
+		result.ContextSize = secondary.ContextSize
+	}
+
+	if result.AdapterOf == "" {
+		result.AdapterOf = secondary.AdapterOf
+	}
+
+	if result.Backend == "" {
+		result.Backend = secondary.Backend
+	}
+
+	if result.Templates == nil && secondary.Templates != nil {
+		result.Templates = secondary.Templates.DeepCopy()
+	}
+
 	return result
 }
 
-func resolveOne(m *a1.AIModel, d *a1.AIDeployment, ctx context.Context, c ctrlClient.Client) (*ResolvedModel, error) {
+// ResolveOne resolves a single model of the deployment, looking up its
+// variant in the referenced AIModelMap if there is one
+func ResolveOne(m *a1.AIModel, d *a1.AIDeployment, modelMapKey string, ctx context.Context, c ctrlClient.Client) (*ResolvedModel, error) {
 	if m.ModelMapRef == nil {
 		return &ResolvedModel{
 			Name:     d.Name,
-			Variant:  "inline",
+			Variant:  InlineVariant,
 			HostName: utils.ToHostName(d.Name + "-model"),
 			Spec:     m.AIModelSpec,
 		}, nil
@@ -95,30 +126,19 @@ func resolveOne(m *a1.AIModel, d *a1.AIDeployment, ctx context.Context, c ctrlCl
 		return nil, fmt.Errorf("deployment %s/%s has modelMapRef with no variant", d.Namespace, d.Name)
 	}
 
+	if modelMapKey == "" {
+		return nil, fmt.Errorf("deployment %s/%s: Can't specify a model map with %s engine", d.Namespace, d.Name, d.Spec.Engine.Name)
+	}
+
 	mm := &a1.AIModelMap{}
 	if err := c.Get(ctx, ctrlClient.ObjectKey{Namespace: namespace, Name: name}, mm); err != nil {
 		return nil, err
 	}
 
-	var variants []a1.AIModelVariant
-	switch d.Spec.Engine.Name {
-	case a1.AIEngineNameTriton:
-		variants = mm.Spec.TensorRT
-	case a1.AIEngineNameLocalai:
-		variants = mm.Spec.Localai
-	case a1.AIEngineNameVLLM:
-		variants = mm.Spec.Vllm
-	case a1.AIEngineNameDeepSpeedMii:
-		variants = mm.Spec.DeepSpeedMii
-	case a1.AIEngineNameGeneric:
-		return nil, fmt.Errorf("deployment %s/%s: Can't specify a model with generic engine", d.Namespace, d.Name)
-	default:
-		return nil, fmt.Errorf("deployment %s/%s has unknown engine %s", d.Namespace, d.Name, d.Spec.Engine.Name)
-	}
-
+	variants := mm.Spec.Variants(modelMapKey)
 	variant := findVariant(variants, m.ModelMapRef.Variant)
 	if variant == nil {
-		return nil, fmt.Errorf("deployment %s/%s has no model variant %s for %s", d.Namespace, d.Name, m.ModelMapRef.Variant, a1.AIEngineNameLocalai)
+		return nil, fmt.Errorf("deployment %s/%s has no model variant %s for %s", d.Namespace, d.Name, m.ModelMapRef.Variant, d.Spec.Engine.Name)
 	}
 
 	merged := mergeModelSpecs(&m.AIModelSpec, variant)
@@ -130,3 +150,33 @@ func resolveOne(m *a1.AIModel, d *a1.AIDeployment, ctx context.Context, c ctrlCl
 		Spec:     *merged,
 	}, nil
 }
+
+// ResolveRef resolves an AIModelMap variant on its own, as it is when it
+// isn't overridden by an AIDeployment. The namespace is used if the
+// reference doesn't have one.
+func ResolveRef(ref a1.AIModelMapReference, namespace string, modelMapKey string, ctx context.Context, c ctrlClient.Client) (*ResolvedModel, error) {
+	if ref.Name == "" || ref.Variant == "" {
+		return nil, fmt.Errorf("model map reference needs a name and a variant")
+	}
+
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	}
+
+	mm := &a1.AIModelMap{}
+	if err := c.Get(ctx, ctrlClient.ObjectKey{Namespace: namespace, Name: ref.Name}, mm); err != nil {
+		return nil, err
+	}
+
+	variant := findVariant(mm.Spec.Variants(modelMapKey), ref.Variant)
+	if variant == nil {
+		return nil, fmt.Errorf("AIModelMap %s/%s has no variant %s for %s", namespace, ref.Name, ref.Variant, modelMapKey)
+	}
+
+	return &ResolvedModel{
+		Name:     ref.Name,
+		Variant:  ref.Variant,
+		HostName: utils.ToHostName(ref.Name + "-" + ref.Variant),
+		Spec:     *variant.DeepCopy(),
+	}, nil
+}
@@ -8,6 +8,16 @@ import (
 	v1 "k8s.io/api/core/v1"
 )
 
+// InlineVariant is the variant of models defined in the AIDeployment rather
+// than an AIModelMap
+const InlineVariant = "inline"
+
+// InlineConfigMapName is the name of the ConfigMap holding the engine config
+// files of an AIDeployment's inline models
+func InlineConfigMapName(deployment string) string {
+	return deployment + "-inline-configs"
+}
+
 func FmtConfigMapKey(engineName a1.AIEngineName, variantName string, fieldName constants.AIModelSpecFieldName) string {
 	return fmt.Sprintf("%s-%s-%s", engineName, variantName, fieldName)
 }
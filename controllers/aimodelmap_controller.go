@@ -31,6 +31,7 @@ import (
 	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
 	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
 	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/engines"
 )
 
 // AIModelMapReconciler reconciles a AIModelMap object
@@ -83,17 +84,12 @@ func (r *AIModelMapReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	newConfigMap := &corev1.ConfigMap{}
 	addedCount := 0
 
-	engines := []struct {
-		name     a1.AIEngineName
-		variants []a1.AIModelVariant
-	}{
-		{name: a1.AIEngineNameLocalai, variants: modelMap.Spec.Localai},
-		{name: a1.AIEngineNameVLLM, variants: modelMap.Spec.Vllm},
-		{name: a1.AIEngineNameGeneric, variants: modelMap.Spec.DeepSpeedMii},
-	}
+	for _, eng := range engines.Definitions() {
+		if eng.ModelMapKey == "" {
+			continue
+		}
 
-	for _, eng := range engines {
-		ac, err := addVariants(newConfigMap, eng.name, eng.variants)
+		ac, err := addVariants(newConfigMap, eng.Name, modelMap.Spec.Variants(eng.ModelMapKey))
 		if err != nil {
 			return ctrl.Result{}, err
 		}
@@ -0,0 +1,425 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/engines"
+	"github.com/premAI-io/prem-operator/controllers/modelcache"
+	"github.com/premAI-io/prem-operator/controllers/resources"
+)
+
+// AIModelPrefetchReconciler reconciles a AIModelPrefetch object
+type AIModelPrefetchReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=premlabs.io,resources=aimodelprefetches,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=premlabs.io,resources=aimodelprefetches/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=premlabs.io,resources=aimodelprefetches/finalizers,verbs=update
+//+kubebuilder:rbac:groups=premlabs.io,resources=aimodelmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+
+// Reconcile resolves the variants of the AIModelPrefetch and applies the
+// DaemonSet which downloads them. Each node which has downloaded a model is
+// given the model's label, see modelcache.NodeLabel, which engines select
+// nodes by. The labels are removed when the model is no longer prefetched
+// onto the node or the AIModelPrefetch is deleted, the files are left.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.17.1/pkg/reconcile
+func (r *AIModelPrefetchReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	p := &a1.AIModelPrefetch{}
+	if err := r.Get(ctx, req.NamespacedName, p); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if p.DeletionTimestamp != nil {
+		if !controllerutil.ContainsFinalizer(p, constants.ModelPrefetchFinalizer) {
+			return ctrl.Result{}, nil
+		}
+
+		if err := r.labelNodes(ctx, p, nil); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		controllerutil.RemoveFinalizer(p, constants.ModelPrefetchFinalizer)
+		return ctrl.Result{}, r.Update(ctx, p)
+	}
+
+	if controllerutil.AddFinalizer(p, constants.ModelPrefetchFinalizer) {
+		if err := r.Update(ctx, p); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	status := p.Status.DeepCopy()
+	status.ObservedGeneration = p.Generation
+
+	models, reason, err := r.resolve(ctx, p)
+	if err != nil {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               constants.ConditionModelsResolved,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: p.Generation,
+			Reason:             reason,
+			Message:            err.Error(),
+		})
+
+		// The AIModelMaps are watched, so this is reconciled again when
+		// they change
+		return r.updateStatus(ctx, p, status)
+	}
+
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               constants.ConditionModelsResolved,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: p.Generation,
+		Reason:             constants.ReasonResolved,
+		Message:            fmt.Sprintf("%d models resolved", len(models)),
+	})
+
+	ds := engines.PrefetchDaemonSet(p, models)
+	ds.OwnerReferences = modelPrefetchOwner(p)
+	if _, err := resources.Apply(ctx, r.Client, ds); err != nil {
+		return ctrl.Result{}, fmt.Errorf("applying DaemonSet %s: %w", ds.Name, err)
+	}
+
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, client.InNamespace(p.Spec.Namespace), client.MatchingLabels{
+		constants.ModelPrefetchLabel: p.Name,
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// A node may have a pod of the previous revision of the DaemonSet while
+	// it is rolled out, the newest pod is the one to go by
+	pods := map[string]*corev1.Pod{}
+	for i, pod := range podList.Items {
+		node := pod.Spec.NodeName
+		if node == "" {
+			continue
+		}
+		if old := pods[node]; old == nil || old.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			pods[node] = &podList.Items[i]
+		}
+	}
+
+	nodes := map[string]*a1.NodePrefetchStatus{}
+	if err := r.labelNodes(ctx, p, func(node *corev1.Node) map[string]bool {
+		s, ready := nodePrefetchStatus(node, pods[node.Name], models)
+		nodes[node.Name] = s
+		return ready
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status.Models = int32(len(models))
+	status.DesiredNodes = 0
+	status.ReadyNodes = 0
+	status.Nodes = []a1.NodePrefetchStatus{}
+	for _, s := range nodes {
+		status.DesiredNodes++
+		if s.Phase == a1.NodePrefetchPhaseReady {
+			status.ReadyNodes++
+		}
+		status.Nodes = append(status.Nodes, *s)
+	}
+	sort.Slice(status.Nodes, func(i, j int) bool { return status.Nodes[i].Node < status.Nodes[j].Node })
+
+	return r.updateStatus(ctx, p, status)
+}
+
+func (r *AIModelPrefetchReconciler) updateStatus(ctx context.Context, p *a1.AIModelPrefetch, status *a1.AIModelPrefetchStatus) (ctrl.Result, error) {
+	if equality.Semantic.DeepEqual(status, &p.Status) {
+		return ctrl.Result{}, nil
+	}
+
+	p.Status = *status
+	err := r.Status().Update(ctx, p)
+	if apierrors.IsConflict(err) {
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	return ctrl.Result{}, err
+}
+
+// resolve looks up the variants of the AIModelPrefetch for its engine. On
+// failure it also returns the reason for the ModelsResolved condition.
+func (r *AIModelPrefetchReconciler) resolve(ctx context.Context, p *a1.AIModelPrefetch) ([]aimodelmap.ResolvedModel, string, error) {
+	engine, ok := engines.Lookup(p.Spec.Engine)
+	if !ok {
+		return nil, constants.ReasonInvalidEngine, fmt.Errorf("unknown engine %s", p.Spec.Engine)
+	}
+	if !engine.ModelPrefetch || engine.ModelMapKey == "" {
+		return nil, constants.ReasonInvalidEngine, fmt.Errorf("the %s engine can't load prefetched models", engine.Name)
+	}
+
+	models := make([]aimodelmap.ResolvedModel, 0, len(p.Spec.Models))
+	keys := map[string]bool{}
+	for _, ref := range p.Spec.Models {
+		m, err := aimodelmap.ResolveRef(ref, p.Spec.Namespace, engine.ModelMapKey, ctx, r.Client)
+		if err != nil {
+			return nil, constants.ReasonResolutionFailed, err
+		}
+
+		if !modelcache.Cacheable(*m) {
+			return nil, constants.ReasonResolutionFailed,
+				fmt.Errorf("variant %s of AIModelMap %s can't be prefetched, its URI %s is fetched by the engine", ref.Variant, ref.Name, m.Spec.Uri)
+		}
+
+		if key := modelcache.Key(*m); !keys[key] {
+			keys[key] = true
+			models = append(models, *m)
+		}
+	}
+
+	return models, "", nil
+}
+
+// labelNodes gives each node the labels returned by ready, for the models
+// which have been prefetched onto it, and removes the other labels the
+// AIModelPrefetch set. The nodes the AIModelPrefetch doesn't select, or all
+// of them if ready is nil, only have their labels removed. A label which was
+// set by another AIModelPrefetch is left as it is.
+func (r *AIModelPrefetchReconciler) labelNodes(ctx context.Context, p *a1.AIModelPrefetch, ready func(*corev1.Node) map[string]bool) error {
+	nodes := &corev1.NodeList{}
+	if err := r.List(ctx, nodes); err != nil {
+		return err
+	}
+
+	value := modelcache.NodeLabelValue(p.Name)
+	selector := labels.SelectorFromSet(p.Spec.NodeSelector)
+
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		patch := client.MergeFrom(node.DeepCopy())
+
+		want := map[string]bool{}
+		if ready != nil && selector.Matches(labels.Set(node.Labels)) {
+			want = ready(node)
+		}
+
+		changed := false
+		for label, v := range node.Labels {
+			if strings.HasPrefix(label, modelcache.PrefetchLabelPrefix) && v == value && !want[label] {
+				delete(node.Labels, label)
+				changed = true
+			}
+		}
+		for label := range want {
+			if _, ok := node.Labels[label]; !ok {
+				if node.Labels == nil {
+					node.Labels = map[string]string{}
+				}
+				node.Labels[label] = value
+				changed = true
+			}
+		}
+
+		if !changed {
+			continue
+		}
+
+		log.FromContext(ctx).Info("Updating prefetched model labels of node", "Node", node.Name)
+		if err := r.Patch(ctx, node, patch); err != nil {
+			return fmt.Errorf("labelling node %s: %w", node.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// nodePrefetchStatus works out the progress of the download onto a node from
+// the init containers of its pod, and returns the labels of the models which
+// are on the node. A model which is already labelled on the node counts as
+// downloaded, so that the node isn't taken away from engines while the
+// models are checked again after the DaemonSet changes.
+func nodePrefetchStatus(node *corev1.Node, pod *corev1.Pod, models []aimodelmap.ResolvedModel) (*a1.NodePrefetchStatus, map[string]bool) {
+	s := &a1.NodePrefetchStatus{
+		Node:  node.Name,
+		Phase: a1.NodePrefetchPhasePending,
+	}
+	ready := map[string]bool{}
+
+	for _, m := range models {
+		label := modelcache.NodeLabel(m)
+		if _, ok := node.Labels[label]; ok {
+			ready[label] = true
+			s.ModelsReady++
+			continue
+		}
+		if pod == nil {
+			continue
+		}
+
+		cs := initContainerStatus(pod, engines.PrefetchContainerName(m))
+		if cs == nil {
+			continue
+		}
+
+		if t := cs.State.Terminated; t != nil && t.ExitCode == 0 {
+			ready[label] = true
+			s.ModelsReady++
+			continue
+		}
+
+		s.Phase = a1.NodePrefetchPhaseDownloading
+		for _, t := range []*corev1.ContainerStateTerminated{cs.State.Terminated, cs.LastTerminationState.Terminated} {
+			if t != nil && t.ExitCode != 0 && s.Message == "" {
+				s.Message = fmt.Sprintf("downloading %s failed: %s", m.Spec.Uri, strings.TrimSpace(t.Message))
+			}
+		}
+	}
+
+	switch {
+	case s.ModelsReady == int32(len(models)):
+		s.Phase = a1.NodePrefetchPhaseReady
+	case s.Message != "":
+		s.Phase = a1.NodePrefetchPhaseFailed
+	}
+
+	return s, ready
+}
+
+func initContainerStatus(pod *corev1.Pod, name string) *corev1.ContainerStatus {
+	for i := range pod.Status.InitContainerStatuses {
+		if pod.Status.InitContainerStatuses[i].Name == name {
+			return &pod.Status.InitContainerStatuses[i]
+		}
+	}
+
+	return nil
+}
+
+func modelPrefetchOwner(p *a1.AIModelPrefetch) []metav1.OwnerReference {
+	return []metav1.OwnerReference{
+		*metav1.NewControllerRef(p, schema.GroupVersionKind{
+			Group:   a1.GroupVersion.Group,
+			Version: a1.GroupVersion.Version,
+			Kind:    "AIModelPrefetch",
+		}),
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager. An
+// AIModelPrefetch is reconciled when its pods change, when nodes are added
+// or relabelled, and when the AIModelMaps it refers to change. The manager
+// only caches the pods labelled constants.ModelDownloadLabel, so the other
+// pods in the cluster aren't watched.
+func (r *AIModelPrefetchReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&a1.AIModelPrefetch{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&appsv1.DaemonSet{}).
+		Watches(
+			&corev1.Pod{},
+			handler.EnqueueRequestsFromMapFunc(r.findPrefetchForPod),
+		).
+		Watches(
+			&corev1.Node{},
+			handler.EnqueueRequestsFromMapFunc(r.findAllPrefetches),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
+		).
+		Watches(
+			&a1.AIModelMap{},
+			handler.EnqueueRequestsFromMapFunc(r.findPrefetchesForModelMap),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Complete(r)
+}
+
+// findPrefetchForPod returns the AIModelPrefetch a download pod belongs to
+func (r *AIModelPrefetchReconciler) findPrefetchForPod(_ context.Context, obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()[constants.ModelPrefetchLabel]
+	if !ok {
+		return nil
+	}
+
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{Name: name},
+	}}
+}
+
+// findAllPrefetches lists every AIModelPrefetch, any of them may select a
+// node
+func (r *AIModelPrefetchReconciler) findAllPrefetches(ctx context.Context, _ client.Object) []reconcile.Request {
+	return r.findPrefetches(ctx, func(*a1.AIModelPrefetch) bool { return true })
+}
+
+// findPrefetchesForModelMap lists the AIModelPrefetches which refer to an
+// AIModelMap
+func (r *AIModelPrefetchReconciler) findPrefetchesForModelMap(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.findPrefetches(ctx, func(p *a1.AIModelPrefetch) bool {
+		return slices.ContainsFunc(p.Spec.Models, func(ref a1.AIModelMapReference) bool {
+			namespace := ref.Namespace
+			if namespace == "" {
+				namespace = p.Spec.Namespace
+			}
+			return namespace == obj.GetNamespace() && ref.Name == obj.GetName()
+		})
+	})
+}
+
+func (r *AIModelPrefetchReconciler) findPrefetches(ctx context.Context, match func(*a1.AIModelPrefetch) bool) []reconcile.Request {
+	prefetches := &a1.AIModelPrefetchList{}
+	if err := r.List(ctx, prefetches); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list AIModelPrefetches")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for i := range prefetches.Items {
+		p := &prefetches.Items[i]
+		if match(p) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: p.Name},
+			})
+		}
+	}
+
+	return requests
+}
@@ -0,0 +1,6 @@
+package constants
+
+const (
+	// FieldManager is the name the operator uses for server-side apply
+	FieldManager = "prem-operator"
+)
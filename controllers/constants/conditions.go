@@ -0,0 +1,38 @@
+package constants
+
+// Condition types set on AIDeployment.Status.Conditions, ModelsResolved is
+// also set on AIModelPrefetch.Status.Conditions
+const (
+	ConditionModelsResolved      = "ModelsResolved"
+	ConditionEngineConfigured    = "EngineConfigured"
+	ConditionDeploymentAvailable = "DeploymentAvailable"
+	ConditionServiceReady        = "ServiceReady"
+	ConditionIngressReady        = "IngressReady"
+	ConditionProgressing         = "Progressing"
+	ConditionResourcesApplied    = "ResourcesApplied"
+	// Only set if the AIDeployment has a modelCacheRef
+	ConditionModelsCached = "ModelsCached"
+)
+
+// Reasons given in AIDeployment conditions
+const (
+	ReasonReconciling      = "Reconciling"
+	ReasonResolved         = "Resolved"
+	ReasonResolutionFailed = "ResolutionFailed"
+	ReasonConfigured       = "Configured"
+	ReasonInvalidConfig    = "InvalidConfig"
+	ReasonApplyFailed      = "ApplyFailed"
+	ReasonApplyConflict    = "ApplyConflict"
+	ReasonSynced           = "Synced"
+	ReasonNoEndpoints      = "NoEndpoints"
+	ReasonAvailable        = "MinimumReplicasAvailable"
+	ReasonUnavailable      = "MinimumReplicasUnavailable"
+	ReasonRollingOut       = "RollingOut"
+	ReasonComplete         = "Complete"
+	ReasonFailed           = "Failed"
+	ReasonCacheNotFound    = "CacheNotFound"
+	ReasonDownloading      = "Downloading"
+	ReasonDownloadFailed   = "DownloadFailed"
+	ReasonCached           = "Cached"
+	ReasonInvalidEngine    = "InvalidEngine"
+)
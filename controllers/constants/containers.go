@@ -2,4 +2,12 @@ package constants
 
 const (
 	ContainerEngineName = "serving"
+
+	// Set in the engine container of multi-node groups
+	EnvLeaderAddress = "LEADER_ADDRESS"
+	EnvGroupSize     = "GROUP_SIZE"
+
+	// The key of a model's credentials Secret given to engines which fetch
+	// the model from the Hugging Face Hub
+	EnvHFToken = "HF_TOKEN"
 )
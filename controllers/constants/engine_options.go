@@ -10,7 +10,29 @@ const (
 	ImageRepositoryDeepSpeedMii = "premai/deepspeed-mii"
 	ImageRepositoryTriton       = "nvcr.io/nvidia/tritonserver"
 	ImageTagTritonDefault       = "24.01-py3"
+	ImageRepositoryTgi          = "ghcr.io/huggingface/text-generation-inference"
+	ImageRepositoryOllama       = "ollama/ollama"
+	ImageRepositoryLlamacpp     = "ghcr.io/ggerganov/llama.cpp"
+	ImageTagLlamacppDefault     = "server"
+	ImageTagLlamacppCuda        = "server-cuda"
+	ImageRepositorySglang       = "lmsysorg/sglang"
+
+	// Has the model downloader used by init containers, it is the operator's
+	// image so this is overridden with the image the operator is deployed from
+	ImageDownloader = "premai/prem-operator:latest"
+	// Keeps the pods of an AIModelPrefetch running once their init
+	// containers have downloaded the models
+	ImagePause = "registry.k8s.io/pause:3.9"
+
+	// Overrides the pull policy of every container the engine adds
+	ImagePullPolicyKey = "imagePullPolicy"
 
 	DtypeKey        = "dtype"
 	QuantizationKey = "quantization"
+	ContextSizeKey  = "contextSize"
+	ThreadsKey      = "threads"
+	GPULayersKey    = "gpuLayers"
+
+	TensorParallelSizeKey = "tensorParallelSize"
+	ShmSizeKey            = "shmSize"
 )
@@ -4,4 +4,25 @@ const (
 	NvidiaGPULabel          = "nvidia.com/gpu"
 	PremSpreadTopologyLabel = "mlcontroller.premlabs.io/spread-topology"
 	PremAIModelMapLabel     = "mlcontroller.premlabs.io/model-map"
+
+	// The role, leader or worker, of a pod in a multi-node group
+	MultiNodeRoleLabel  = "mlcontroller.premlabs.io/multi-node-role"
+	MultiNodeGroupLabel = "mlcontroller.premlabs.io/multi-node-group"
+	MultiNodeRoleLeader = "leader"
+	MultiNodeRoleWorker = "worker"
+
+	// Set on the Jobs which download models into a ModelCache and on their
+	// pods, the key is the model's directory in the cache
+	ModelCacheLabel    = "mlcontroller.premlabs.io/model-cache"
+	ModelCacheKeyLabel = "mlcontroller.premlabs.io/model-cache-key"
+
+	// Set on the DaemonSet which downloads the models of an AIModelPrefetch
+	// onto nodes and on its pods, the value is the AIModelPrefetch's name
+	ModelPrefetchLabel = "mlcontroller.premlabs.io/model-prefetch"
+	// Keeps an AIModelPrefetch until the labels it set on nodes are removed
+	ModelPrefetchFinalizer = "mlcontroller.premlabs.io/model-prefetch"
+
+	// Set, to "true", on the pods which download models for a ModelCache or
+	// an AIModelPrefetch. These are the only pods the manager caches.
+	ModelDownloadLabel = "mlcontroller.premlabs.io/model-download"
 )
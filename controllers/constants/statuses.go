@@ -1,9 +0,0 @@
-package constants
-
-const (
-	Ready    Status = "Ready"
-	NotReady Status = "NotReady"
-	Failed   Status = "Failed"
-)
-
-type Status string
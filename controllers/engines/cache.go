@@ -0,0 +1,124 @@
+package engines
+
+import (
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/modelcache"
+	"github.com/premAI-io/prem-operator/pkg/downloader"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	modelCacheVolumeName = "model-cache"
+	// The downloader retries by itself, so the Job only retries a pod which
+	// is lost
+	modelCacheJobBackoffLimit = 2
+)
+
+// cached is true if m is loaded from the AIDeployment's ModelCache
+func cached(ai *a1.AIDeployment, m aimodelmap.ResolvedModel) bool {
+	return ai.Spec.ModelCacheRef != nil && modelcache.Cacheable(m)
+}
+
+func modelCacheVolume(cache string, readOnly bool) v1.Volume {
+	return v1.Volume{
+		Name: modelCacheVolumeName,
+		VolumeSource: v1.VolumeSource{
+			PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+				ClaimName: modelcache.ClaimName(cache),
+				ReadOnly:  readOnly,
+			},
+		},
+	}
+}
+
+// addModelCacheVolume adds the AIDeployment's ModelCache to the pod, read-only,
+// unless it has been added already
+func addModelCacheVolume(ai *a1.AIDeployment, pod *v1.PodSpec) {
+	for _, v := range pod.Volumes {
+		if v.Name == modelCacheVolumeName {
+			return
+		}
+	}
+
+	pod.Volumes = append(pod.Volumes, modelCacheVolume(ai.Spec.ModelCacheRef.Name, true))
+}
+
+// modelCacheMount mounts the whole ModelCache read-only, models are then in
+// modelcache.Dir
+func modelCacheMount() v1.VolumeMount {
+	return v1.VolumeMount{
+		Name:      modelCacheVolumeName,
+		MountPath: modelcache.MountPath,
+		ReadOnly:  true,
+	}
+}
+
+// cachedModelMount mounts m from the ModelCache at path, its file with file
+// and otherwise its directory. This is for engines which load a model from a
+// fixed path rather than the one given by modelPath.
+func cachedModelMount(m aimodelmap.ResolvedModel, file bool, path string) v1.VolumeMount {
+	return v1.VolumeMount{
+		Name:      modelCacheVolumeName,
+		MountPath: path,
+		SubPath:   modelcache.SubPath(m, file),
+		ReadOnly:  true,
+	}
+}
+
+// ModelCacheJob creates the Job which downloads m into the cache, in the
+// same way addModelDownload would download it into the pod. With file the
+// file at m's URI is stored as it is, see Definition.CachedAsFile. The Job is
+// given the env of ai, the first AIDeployment to use the model, as well as
+// the model's credentials. Its pod reports the size of the model in its
+// termination message, or the end of its log if it fails.
+func ModelCacheJob(cache *a1.ModelCache, ai *a1.AIDeployment, m aimodelmap.ResolvedModel, file bool) *batchv1.Job {
+	key := modelcache.Key(m)
+	labels := map[string]string{
+		constants.ModelCacheLabel:    cache.Name,
+		constants.ModelCacheKeyLabel: key,
+	}
+
+	output := modelcache.Dir(m)
+	if file {
+		output = modelcache.MountPath + "/" + modelcache.SubPath(m, true)
+	}
+
+	container := downloadContainer(ai.Spec.Env, "download", m.Spec, output, !file && !downloader.IsDirectory(m.Spec.Uri), v1.VolumeMount{
+		Name:      modelCacheVolumeName,
+		MountPath: modelcache.MountPath,
+	})
+	container.Args = append(container.Args, "--report-size", v1.TerminationMessagePathDefault)
+	container.TerminationMessagePolicy = v1.TerminationMessageFallbackToLogsOnError
+
+	backoffLimit := int32(modelCacheJobBackoffLimit)
+	// The claim is made writable by the downloader with fsGroup
+	fsGroup := int64(downloaderUser)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      modelcache.JobName(cache.Name, key),
+			Namespace: cache.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: downloadPodLabels(labels),
+				},
+				Spec: v1.PodSpec{
+					RestartPolicy: v1.RestartPolicyNever,
+					SecurityContext: &v1.PodSecurityContext{
+						FSGroup: &fsGroup,
+					},
+					Containers: []v1.Container{container},
+					Volumes:    []v1.Volume{modelCacheVolume(cache.Name, false)},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,126 @@
+package engines_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/engines"
+	"github.com/premAI-io/prem-operator/controllers/modelcache"
+)
+
+// cachedMount finds the mount of the ModelCache in a container
+func cachedMount(c corev1.Container) *corev1.VolumeMount {
+	for i, m := range c.VolumeMounts {
+		if m.Name == "model-cache" {
+			return &c.VolumeMounts[i]
+		}
+	}
+
+	return nil
+}
+
+var _ = Describe("ModelCache", func() {
+	DescribeTable("ModelCacheJob",
+		func(uri string, file bool, output string, extract bool) {
+			cache := &a1.ModelCache{ObjectMeta: metav1.ObjectMeta{Name: "models", Namespace: "default"}}
+			m := model(uri)
+
+			job := engines.ModelCacheJob(cache, newDeployment(a1.AIEngineNameVLLM), m, file)
+			args := job.Spec.Template.Spec.Containers[0].Args
+			Expect(argValue(args, "--output")).To(Equal(output))
+			if extract {
+				Expect(args).To(ContainElement("--extract"))
+			} else {
+				Expect(args).NotTo(ContainElement("--extract"))
+			}
+		},
+		Entry("an archive", "https://example.com/opt-125m.tar", false,
+			modelcache.MountPath+"/"+modelcache.Key(model("https://example.com/opt-125m.tar")), true),
+		Entry("a directory", "s3://models/opt-125m/", false,
+			modelcache.MountPath+"/"+modelcache.Key(model("s3://models/opt-125m/")), false),
+		Entry("a file", "https://example.com/phi-2.Q4_K_M.gguf?download=true", true,
+			modelcache.MountPath+"/"+modelcache.Key(model("https://example.com/phi-2.Q4_K_M.gguf?download=true"))+"/phi-2.Q4_K_M.gguf", false),
+	)
+
+	DescribeTable("CachedAsFile",
+		func(name a1.AIEngineName, uri string, file bool) {
+			def, ok := engines.Lookup(name)
+			Expect(ok).To(BeTrue())
+			Expect(def.CachedAsFile(model(uri))).To(Equal(file))
+		},
+		Entry("vLLM unpacks a file", a1.AIEngineNameVLLM, "https://example.com/opt-125m.tar", false),
+		Entry("llama.cpp loads a file", a1.AIEngineNameLlamacpp, "https://example.com/phi-2.gguf", true),
+		Entry("LocalAI loads a file", a1.AIEngineNameLocalai, "https://example.com/phi-2.gguf", true),
+		Entry("LocalAI loads a directory", a1.AIEngineNameLocalai, "s3://models/phi-2/", false),
+		Entry("Triton loads a file", a1.AIEngineNameTriton, "https://example.com/model.onnx", true),
+		Entry("Triton unpacks an archive", a1.AIEngineNameTriton, "https://example.com/repository.tar.gz", false),
+	)
+
+	It("mounts the GGUF file of llama.cpp", func() {
+		m := model("https://example.com/phi-2.gguf")
+		d := engineDeployment(a1.AIEngineNameLlamacpp, m, withModelCache)
+		pod := d.Spec.Template.Spec
+
+		Expect(pod.InitContainers).To(BeEmpty())
+		Expect(pod.Volumes).To(ConsistOf(HaveField("PersistentVolumeClaim", HaveValue(And(
+			HaveField("ClaimName", "models"),
+			HaveField("ReadOnly", true),
+		)))))
+		Expect(pod.Containers[0].VolumeMounts).To(ConsistOf(corev1.VolumeMount{
+			Name:      "model-cache",
+			MountPath: "/models/model.gguf",
+			SubPath:   modelcache.Key(m) + "/phi-2.gguf",
+			ReadOnly:  true,
+		}))
+		Expect(argValue(pod.Containers[0].Args, "--model")).To(Equal("/models/model.gguf"))
+	})
+
+	DescribeTable("mounts the models of LocalAI",
+		func(uri string, subPath func(aimodelmap.ResolvedModel) string) {
+			m := model(uri)
+			d := engineDeployment(a1.AIEngineNameLocalai, m, withModelCache)
+			pod := d.Spec.Template.Spec
+
+			Expect(pod.InitContainers).To(BeEmpty())
+			Expect(hasVolume(d, "model-cache")).To(BeTrue())
+			Expect(cachedMount(pod.Containers[0])).To(HaveValue(Equal(corev1.VolumeMount{
+				Name:      "model-cache",
+				MountPath: "/models/llm",
+				SubPath:   subPath(m),
+				ReadOnly:  true,
+			})))
+		},
+		Entry("a file", "https://example.com/phi-2.gguf",
+			func(m aimodelmap.ResolvedModel) string { return modelcache.Key(m) + "/phi-2.gguf" }),
+		Entry("a directory", "s3://models/phi-2/", modelcache.Key),
+	)
+
+	DescribeTable("links the models of Triton",
+		func(uri, env string) {
+			m := model(uri)
+			d := engineDeployment(a1.AIEngineNameTriton, m, withModelCache)
+			pod := d.Spec.Template.Spec
+
+			Expect(pod.InitContainers).To(HaveLen(1))
+			init := pod.InitContainers[0]
+			Expect(init.Name).To(Equal("init-llm-model"))
+			Expect(init.Env).To(ContainElement(corev1.EnvVar{Name: env, Value: modelcache.Dir(m)}))
+			Expect(cachedMount(init)).To(HaveValue(HaveField("ReadOnly", true)))
+
+			engine := pod.Containers[0]
+			Expect(engine.Name).To(Equal(constants.ContainerEngineName))
+			Expect(cachedMount(engine)).To(HaveValue(And(
+				HaveField("MountPath", modelcache.MountPath),
+				HaveField("ReadOnly", true),
+			)))
+		},
+		Entry("a file", "https://example.com/model.onnx", "CACHED_VERSION"),
+		Entry("a directory", "s3://models/tokenizer/", "CACHED_VERSION"),
+		Entry("an archive", "https://example.com/repository.tar.gz", "CACHED_REPOSITORY"),
+	)
+})
@@ -2,12 +2,11 @@ package engines
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/premAI-io/prem-operator/controllers/aideployment"
 	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
 	"github.com/premAI-io/prem-operator/controllers/constants"
-	"github.com/premAI-io/prem-operator/controllers/resources"
-	"github.com/premAI-io/prem-operator/pkg/utils"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -16,34 +15,36 @@ import (
 	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
 )
 
+const deepSpeedMiiPort = int32(8080)
+
 type DeepSpeedMii struct {
 	AIDeployment *a1.AIDeployment
 	model        aimodelmap.ResolvedModel
 }
 
 func NewDeepSpeedMii(ai *a1.AIDeployment, models []aimodelmap.ResolvedModel) (aideployment.MLEngine, error) {
-	if len(models) == 0 {
-		return nil, ErrModelsNotSpecified
-	}
-
-	if len(models) > 1 {
-		return nil, ErrorOnlyOneModel
+	if err := singleModel(ai, models); err != nil {
+		return nil, err
 	}
 
 	return &DeepSpeedMii{AIDeployment: ai, model: models[0]}, nil
 }
 
+func init() {
+	Register(Definition{
+		Name:        a1.AIEngineNameDeepSpeedMii,
+		ModelMapKey: a1.AIModelMapKeyDeepSpeedMii,
+		DefaultPort: deepSpeedMiiPort,
+		New:         NewDeepSpeedMii,
+		Validate:    singleModel,
+	})
+}
+
 func (l *DeepSpeedMii) Port() int32 {
-	return 8080
+	return deepSpeedMiiPort
 }
 
 func (l *DeepSpeedMii) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
-	objMeta := metav1.ObjectMeta{
-		Name:            l.AIDeployment.Name,
-		Namespace:       l.AIDeployment.Namespace,
-		OwnerReferences: resources.GenOwner(owner),
-	}
-
 	imageTag := constants.ImageTagLatest
 	if l.AIDeployment.Spec.Engine.Options[constants.ImageTagKey] != "" {
 		imageTag = l.AIDeployment.Spec.Engine.Options[constants.ImageTagKey]
@@ -54,13 +55,7 @@ func (l *DeepSpeedMii) Deployment(owner metav1.Object) (*appsv1.Deployment, erro
 		imageRepository = l.AIDeployment.Spec.Engine.Options[constants.ImageRepositoryKey]
 	}
 
-	deployment := appsv1.Deployment{}
-	if l.AIDeployment.Spec.Deployment.PodTemplate != nil {
-		deployment.Spec.Template = *l.AIDeployment.Spec.Deployment.PodTemplate.DeepCopy()
-	} else {
-		deployment.Spec.Template = v1.PodTemplateSpec{}
-	}
-	deployment.Spec.Replicas = l.AIDeployment.Spec.Deployment.Replicas
+	deployment := newDeployment(l.AIDeployment, owner)
 	pod := &deployment.Spec.Template.Spec
 
 	if pod.ImagePullSecrets == nil {
@@ -69,8 +64,6 @@ func (l *DeepSpeedMii) Deployment(owner metav1.Object) (*appsv1.Deployment, erro
 		}
 	}
 
-	serviceAccount := false
-
 	image := fmt.Sprintf("%s:%s", imageRepository, imageTag)
 
 	backendProbeHandler := v1.ProbeHandler{
@@ -112,27 +105,24 @@ func (l *DeepSpeedMii) Deployment(owner metav1.Object) (*appsv1.Deployment, erro
 		},
 	}
 
+	tp, err := tensorParallelSize(l.AIDeployment)
+	if err != nil {
+		return nil, err
+	}
+	if tp > 1 {
+		container.Args = append(container.Args, "--tensor-parallel", strconv.FormatInt(tp, 10))
+	}
+	if err := addSharedMemory(l.AIDeployment, tp, pod, &container); err != nil {
+		return nil, err
+	}
+
 	mergeProbe(l.AIDeployment.Spec.Deployment.StartupProbe, container.StartupProbe)
 	mergeProbe(l.AIDeployment.Spec.Deployment.ReadinessProbe, container.ReadinessProbe)
 	mergeProbe(l.AIDeployment.Spec.Deployment.LivenessProbe, container.LivenessProbe)
 
-	pod.AutomountServiceAccountToken = &serviceAccount
-
-	pod.Containers = append(pod.Containers, container)
-	deploymentLabels := resources.GenDefaultLabels(l.AIDeployment.Name)
-	deployment.Spec.Template.Labels = utils.MergeMaps(
-		deploymentLabels,
-		deployment.Spec.Template.Labels,
-		l.AIDeployment.Spec.Deployment.Labels,
-	)
-
-	deployment.Spec.Template.Annotations = utils.MergeMaps(
-		deployment.Spec.Template.Annotations,
-		l.AIDeployment.Spec.Deployment.Annotations,
-	)
-
-	deployment.ObjectMeta = objMeta
-	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: deploymentLabels}
+	if err := addEngineContainers(l.AIDeployment, pod, container); err != nil {
+		return nil, err
+	}
 
-	return &deployment, nil
+	return deployment, nil
 }
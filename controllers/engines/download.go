@@ -0,0 +1,148 @@
+package engines
+
+import (
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/modelcache"
+	"github.com/premAI-io/prem-operator/pkg/downloader"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	downloadsVolumeName = "downloads"
+	downloadsMountPath  = "/downloads"
+	// The user, and group, the operator's image runs the downloader as
+	downloaderUser = 65532
+)
+
+// DownloaderImage is the image init containers run the model downloader
+// from, which is the operator's own image. The manager sets it from the
+// DOWNLOADER_IMAGE env var.
+var DownloaderImage = constants.ImageDownloader
+
+// downloadContainer creates an init container which downloads the model at
+// spec's URI to output with the operator's downloader, checking it against
+// spec's sha256 if that is set. With extract, or if the URI is a directory,
+// output is a directory. A file which was already downloaded, or an archive
+// which was already extracted, isn't downloaded again. The keys of the model's
+// credentials Secret are given to it as env vars, as well as env, usually the
+// AIDeployment's, which takes precedence.
+func downloadContainer(env []v1.EnvVar, name string, spec a1.AIModelSpec, output string, extract bool, mounts ...v1.VolumeMount) v1.Container {
+	args := []string{"--url", spec.Uri, "--output", output}
+	if spec.Sha256 != "" {
+		args = append(args, "--sha256", spec.Sha256)
+	}
+	if extract {
+		args = append(args, "--extract")
+	}
+
+	container := v1.Container{
+		ImagePullPolicy: v1.PullIfNotPresent,
+		Name:            name,
+		Image:           DownloaderImage,
+		Command:         []string{"/downloader"},
+		Args:            args,
+		Env:             env,
+		VolumeMounts:    mounts,
+	}
+
+	if spec.CredentialsSecretRef != nil {
+		container.EnvFrom = []v1.EnvFromSource{{
+			SecretRef: &v1.SecretEnvSource{LocalObjectReference: *spec.CredentialsSecretRef},
+		}}
+	}
+
+	return container
+}
+
+// downloadPodLabels returns labels with the label of the pods which download
+// models added, so the manager caches the pod. labels isn't modified, as it is
+// also the selector of the pod's owner.
+func downloadPodLabels(labels map[string]string) map[string]string {
+	podLabels := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		podLabels[k] = v
+	}
+	podLabels[constants.ModelDownloadLabel] = "true"
+
+	return podLabels
+}
+
+// hfTokenEnv returns the env of a container which fetches the model at spec
+// from the Hugging Face Hub itself. It has the HF_TOKEN key of the model's
+// credentials Secret, if it has one, followed by env.
+func hfTokenEnv(spec a1.AIModelSpec, env []v1.EnvVar) []v1.EnvVar {
+	if spec.CredentialsSecretRef == nil || downloader.Supported(spec.Uri) {
+		return env
+	}
+
+	optional := true
+	token := v1.EnvVar{
+		Name: constants.EnvHFToken,
+		ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{
+				LocalObjectReference: *spec.CredentialsSecretRef,
+				Key:                  constants.EnvHFToken,
+				Optional:             &optional,
+			},
+		},
+	}
+
+	return append([]v1.EnvVar{token}, env...)
+}
+
+// modelPath is what an engine which loads models by name, e.g. from the
+// Hugging Face Hub, is given for m. It is the directory the downloader puts
+// the model in if the downloader can fetch its URI, see addModelDownload.
+// That is in the AIDeployment's ModelCache if it has one. Otherwise it is the
+// URI.
+func modelPath(ai *a1.AIDeployment, m aimodelmap.ResolvedModel) string {
+	if !downloader.Supported(m.Spec.Uri) {
+		return m.Spec.Uri
+	}
+
+	if cached(ai, m) {
+		return modelcache.Dir(m)
+	}
+
+	return downloadsMountPath + "/" + m.HostName
+}
+
+// addModelDownload adds the volume the model is downloaded to, for engines
+// which use modelPath, and returns the init container which downloads it. A
+// URI of a single file is taken to be a tar archive of the model's
+// directory. There is nothing to add if the engine fetches the model itself.
+// A model in the AIDeployment's ModelCache is already downloaded, so only the
+// cache is mounted. A prefetched model is downloaded unless the pod is on a
+// node which has it, see addPrefetchedModel.
+func addModelDownload(ai *a1.AIDeployment, m aimodelmap.ResolvedModel, pod *v1.PodSpec, container *v1.Container) []v1.Container {
+	if !downloader.Supported(m.Spec.Uri) {
+		return nil
+	}
+
+	if cached(ai, m) {
+		addModelCacheVolume(ai, pod)
+		container.VolumeMounts = append(container.VolumeMounts, modelCacheMount())
+		return nil
+	}
+
+	mount := v1.VolumeMount{
+		Name:      downloadsVolumeName,
+		MountPath: downloadsMountPath,
+	}
+	pod.Volumes = append(pod.Volumes, v1.Volume{
+		Name: downloadsVolumeName,
+		VolumeSource: v1.VolumeSource{
+			EmptyDir: &v1.EmptyDirVolumeSource{},
+		},
+	})
+	container.VolumeMounts = append(container.VolumeMounts, mount)
+
+	download := downloadContainer(ai.Spec.Env, "init-download-model", m.Spec, modelPath(ai, m), !downloader.IsDirectory(m.Spec.Uri), mount)
+	if prefetched(m) {
+		addPrefetchedModel(m, pod, container, &download)
+	}
+
+	return []v1.Container{download}
+}
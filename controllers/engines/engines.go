@@ -0,0 +1,128 @@
+package engines
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aideployment"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+)
+
+// Definition describes an engine to the operator. The built-in engines
+// register themselves when this package is imported, others can be added
+// with Register before the manager is started.
+type Definition struct {
+	Name a1.AIEngineName
+	// ModelMapKey is where the engine's variants are found in an
+	// AIModelMap, see AIModelMapSpec.Variants. If it is empty then the
+	// engine can't be used with a modelMapRef.
+	ModelMapKey string
+	// DefaultPort is the port the engine serves on
+	DefaultPort int32
+	// New creates the engine for an AIDeployment and its resolved models
+	New func(ai *a1.AIDeployment, models []aimodelmap.ResolvedModel) (aideployment.MLEngine, error)
+	// Validate checks the AIDeployment and models can be used with the
+	// engine, it is called before New and by the admission webhook. It may
+	// be nil.
+	Validate func(ai *a1.AIDeployment, models []aimodelmap.ResolvedModel) error
+	// ValidateEngineConfig checks an engineConfigFile in an AIModelMap
+	// variant. If it is nil then the config is not checked.
+	ValidateEngineConfig func(config string) error
+	// ModelCache is true if the engine can load its models from a
+	// ModelCache, see AIDeploymentSpec.ModelCacheRef. The cache is mounted
+	// read-only and each model is in its own directory, see modelcache.Dir.
+	ModelCache bool
+	// ModelCacheFile is true if the engine loads m from a single file, which
+	// a ModelCache then stores as it is, see modelcache.SubPath. Otherwise a
+	// URI of a single file is taken to be a tar archive of the model's
+	// directory and unpacked. It may be nil.
+	ModelCacheFile func(m aimodelmap.ResolvedModel) bool
+	// ModelPrefetch is true if the engine can load models which an
+	// AIModelPrefetch downloaded onto its node, which it does if it
+	// downloads models with addModelDownload
+	ModelPrefetch bool
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[a1.AIEngineName]Definition{}
+)
+
+// Register adds an engine to the registry. It panics if the definition is
+// incomplete or an engine with the same name is already registered.
+func Register(def Definition) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if def.Name == "" || def.New == nil {
+		panic("engines: Register called with no engine name or constructor")
+	}
+
+	if _, dup := registry[def.Name]; dup {
+		panic(fmt.Sprintf("engines: Register called twice for engine %s", def.Name))
+	}
+
+	registry[def.Name] = def
+}
+
+// Lookup finds a registered engine by name
+func Lookup(name a1.AIEngineName) (Definition, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	def, ok := registry[name]
+	return def, ok
+}
+
+// Definitions lists the registered engines sorted by name
+func Definitions() []Definition {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	defs := make([]Definition, 0, len(registry))
+	for _, def := range registry {
+		defs = append(defs, def)
+	}
+
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+
+	return defs
+}
+
+// modelCacheEngines are the names of the engines which can load their models
+// from a ModelCache
+func modelCacheEngines() []string {
+	names := []string{}
+	for _, def := range Definitions() {
+		if def.ModelCache {
+			names = append(names, string(def.Name))
+		}
+	}
+
+	return names
+}
+
+// CachedAsFile is true if a ModelCache stores m as a single file for the
+// engine, see ModelCacheFile
+func (def Definition) CachedAsFile(m aimodelmap.ResolvedModel) bool {
+	return def.ModelCacheFile != nil && def.ModelCacheFile(m)
+}
+
+// Create validates then creates the engine described by def
+func (def Definition) Create(ai *a1.AIDeployment, models []aimodelmap.ResolvedModel) (aideployment.MLEngine, error) {
+	if ai.Spec.ModelCacheRef != nil && !def.ModelCache {
+		return nil, fmt.Errorf("the %s engine can't load models from a ModelCache, only %s can",
+			def.Name, strings.Join(modelCacheEngines(), ", "))
+	}
+
+	if def.Validate != nil {
+		if err := def.Validate(ai, models); err != nil {
+			return nil, err
+		}
+	}
+
+	return def.New(ai, models)
+}
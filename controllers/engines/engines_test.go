@@ -0,0 +1,156 @@
+package engines_test
+
+import (
+	"context"
+	"sort"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aideployment"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/engines"
+)
+
+func newClient(objs ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	Expect(a1.AddToScheme(scheme)).To(Succeed())
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func newDeployment(engine a1.AIEngineName) *a1.AIDeployment {
+	return &a1.AIDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "llm", Namespace: "default"},
+		Spec: a1.AIDeploymentSpec{
+			Engine: a1.AIEngine{Name: engine},
+		},
+	}
+}
+
+func model(uri string) aimodelmap.ResolvedModel {
+	return aimodelmap.ResolvedModel{
+		Name:     "llm",
+		Variant:  aimodelmap.InlineVariant,
+		HostName: "llm-model",
+		Spec:     a1.AIModelSpec{Uri: uri},
+	}
+}
+
+var _ = Describe("Registry", func() {
+	It("has the built-in engines sorted by name", func() {
+		names := []a1.AIEngineName{}
+		for _, def := range engines.Definitions() {
+			names = append(names, def.Name)
+		}
+
+		Expect(names).To(ContainElements(
+			a1.AIEngineNameVLLM, a1.AIEngineNameTgi, a1.AIEngineNameSglang, a1.AIEngineNameLocalai,
+		))
+		Expect(sort.SliceIsSorted(names, func(i, j int) bool { return names[i] < names[j] })).To(BeTrue())
+	})
+
+	It("panics on an incomplete or duplicate definition", func() {
+		Expect(func() { engines.Register(engines.Definition{Name: "incomplete"}) }).To(Panic())
+		Expect(func() {
+			engines.Register(engines.Definition{
+				Name: a1.AIEngineNameVLLM,
+				New: func(*a1.AIDeployment, []aimodelmap.ResolvedModel) (aideployment.MLEngine, error) {
+					return nil, nil
+				},
+			})
+		}).To(Panic())
+	})
+
+	It("registers a new engine", func() {
+		def := engines.Definition{
+			Name:        "registered-in-test",
+			ModelMapKey: "registered-in-test",
+			New: func(*a1.AIDeployment, []aimodelmap.ResolvedModel) (aideployment.MLEngine, error) {
+				return nil, nil
+			},
+		}
+		engines.Register(def)
+
+		found, ok := engines.Lookup(def.Name)
+		Expect(ok).To(BeTrue())
+		Expect(found.ModelMapKey).To(Equal(def.ModelMapKey))
+	})
+
+	DescribeTable("Get",
+		func(name a1.AIEngineName, objs []client.Object, modelMapKey string, err error) {
+			def, e := engines.Get(context.Background(), newClient(objs...), newDeployment(name))
+			if err != nil {
+				Expect(e).To(MatchError(err))
+				return
+			}
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(def.Name).To(Equal(name))
+			Expect(def.ModelMapKey).To(Equal(modelMapKey))
+		},
+		Entry("a built-in engine", a1.AIEngineNameVLLM, nil, a1.AIModelMapKeyVllm, nil),
+		Entry("an AIEngineTemplate",
+			a1.AIEngineName("custom"),
+			[]client.Object{&a1.AIEngineTemplate{ObjectMeta: metav1.ObjectMeta{Name: "custom"}}},
+			"custom", nil),
+		Entry("an AIEngineTemplate with a model map key",
+			a1.AIEngineName("custom"),
+			[]client.Object{&a1.AIEngineTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "custom"},
+				Spec:       a1.AIEngineTemplateSpec{ModelMapKey: "shared"},
+			}},
+			"shared", nil),
+		Entry("an unknown engine", a1.AIEngineName("no-such-engine"), nil, "", engines.ErrUnknownEngine),
+	)
+
+	DescribeTable("Create",
+		func(name a1.AIEngineName, mutate func(*a1.AIDeployment), models []aimodelmap.ResolvedModel, err string) {
+			def, ok := engines.Lookup(name)
+			Expect(ok).To(BeTrue())
+
+			ai := newDeployment(name)
+			if mutate != nil {
+				mutate(ai)
+			}
+
+			mle, e := def.Create(ai, models)
+			if err != "" {
+				Expect(e).To(MatchError(ContainSubstring(err)))
+				return
+			}
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(mle).NotTo(BeNil())
+		},
+		Entry("one model", a1.AIEngineNameTgi, nil,
+			[]aimodelmap.ResolvedModel{model("facebook/opt-125m")}, ""),
+		Entry("multiple models on a single model engine", a1.AIEngineNameTgi, nil,
+			[]aimodelmap.ResolvedModel{model("facebook/opt-125m"), model("facebook/opt-350m")},
+			"only one model can be specified"),
+		Entry("no models", a1.AIEngineNameSglang, nil, nil, "models not specified"),
+		Entry("an ensemble on Triton", a1.AIEngineNameTriton, nil,
+			[]aimodelmap.ResolvedModel{model("https://example.com/model.onnx"), {
+				Name:     "tokenizer",
+				Variant:  "python",
+				HostName: "tokenizer-python",
+				Spec:     a1.AIModelSpec{Uri: "https://example.com/model.py"},
+			}}, ""),
+		Entry("two inline models on Triton", a1.AIEngineNameTriton, nil,
+			[]aimodelmap.ResolvedModel{model("https://example.com/model.onnx"), model("https://example.com/model.py")},
+			"more than one model is named llm-model in the model repository"),
+		Entry("a ModelCache", a1.AIEngineNameVLLM,
+			func(ai *a1.AIDeployment) { ai.Spec.ModelCacheRef = &corev1.LocalObjectReference{Name: "models"} },
+			[]aimodelmap.ResolvedModel{model("s3://models/opt-125m/")}, ""),
+		Entry("a ModelCache on an engine which can't use one", a1.AIEngineNameOllama,
+			func(ai *a1.AIDeployment) { ai.Spec.ModelCacheRef = &corev1.LocalObjectReference{Name: "models"} },
+			[]aimodelmap.ResolvedModel{model("llama3")},
+			"the ollama engine can't load models from a ModelCache, only llamacpp, localai, sglang, tgi, triton, vllm can"),
+	)
+})
@@ -2,22 +2,35 @@ package engines
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/premAI-io/prem-operator/controllers/aideployment"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
 	"github.com/premAI-io/prem-operator/controllers/constants"
-	"github.com/premAI-io/prem-operator/pkg/utils"
 
 	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
-	"github.com/premAI-io/prem-operator/controllers/resources"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+const genericPort = int32(8000)
+
 type Generic struct {
 	AIDeployment *a1.AIDeployment
 }
 
+func init() {
+	Register(Definition{
+		Name:        a1.AIEngineNameGeneric,
+		DefaultPort: genericPort,
+		New: func(ai *a1.AIDeployment, _ []aimodelmap.ResolvedModel) (aideployment.MLEngine, error) {
+			return NewGeneric(ai), nil
+		},
+	})
+}
+
 func NewGeneric(ai *a1.AIDeployment) aideployment.MLEngine {
 	return &Generic{AIDeployment: ai}
 
@@ -27,66 +40,76 @@ func (l *Generic) Port() int32 {
 	if len(l.AIDeployment.Spec.Endpoint) > 0 {
 		return l.AIDeployment.Spec.Endpoint[0].Port
 	} else {
-		return int32(8000)
+		return genericPort
 	}
 }
 
-func (l *Generic) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
-	objMeta := metav1.ObjectMeta{
-		Name:            l.AIDeployment.Name,
-		Namespace:       l.AIDeployment.Namespace,
-		OwnerReferences: resources.GenOwner(owner),
+// ServicePorts exposes every port which an endpoint uses
+func (l *Generic) ServicePorts() []v1.ServicePort {
+	ports := []v1.ServicePort{}
+	for _, ep := range l.AIDeployment.Spec.Endpoint {
+		if ep.Port == 0 || slices.ContainsFunc(ports, func(p v1.ServicePort) bool { return p.Port == ep.Port }) {
+			continue
+		}
+
+		ports = append(ports, v1.ServicePort{
+			Name: fmt.Sprintf("port-%d", ep.Port), Port: ep.Port, TargetPort: intstr.FromInt(int(ep.Port)),
+		})
 	}
 
-	deployment := appsv1.Deployment{}
-	if l.AIDeployment.Spec.Deployment.PodTemplate != nil {
-		deployment.Spec.Template = *l.AIDeployment.Spec.Deployment.PodTemplate.DeepCopy()
-	} else {
-		return nil, fmt.Errorf("Generic AI deployment %s:%s requires a pod template", objMeta.Namespace, objMeta.Name)
+	// Single port Services don't need a name
+	if len(ports) < 2 {
+		return []v1.ServicePort{
+			{Port: l.Port(), TargetPort: intstr.FromInt(int(l.Port()))},
+		}
 	}
-	deployment.Spec.Replicas = l.AIDeployment.Spec.Deployment.Replicas
+
+	return ports
+}
+
+func (l *Generic) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
+	ai := l.AIDeployment
+	if ai.Spec.Deployment.PodTemplate == nil {
+		return nil, fmt.Errorf("Generic AI deployment %s:%s requires a pod template", ai.Namespace, ai.Name)
+	}
+
+	deployment := newDeployment(ai, owner)
 	pod := &deployment.Spec.Template.Spec
 
-	serviceAccount := false
+	if len(pod.Containers) == 0 {
+		return nil, fmt.Errorf("Generic AI deployment %s:%s requires at least one container in the pod template", ai.Namespace, ai.Name)
+	}
 
 	expose := &pod.Containers[0]
 	expose.Name = constants.ContainerEngineName
 
 	if len(expose.Env) > 0 {
-		return nil, fmt.Errorf("Generic AI deployment %s:%s: Specify env vars for the first container in the AIDeployment, not the container", objMeta.Namespace, objMeta.Name)
+		return nil, fmt.Errorf("Generic AI deployment %s:%s: Specify env vars for the first container in the AIDeployment, not the container", ai.Namespace, ai.Name)
 	}
-	expose.Env = l.AIDeployment.Spec.Env
+	expose.Env = ai.Spec.Env
 
 	if len(expose.Ports) > 0 {
-		return nil, fmt.Errorf("Generic AI deployment %s:%s: Specify ports in AIDeployment.Spec.Endpoint not the container", objMeta.Namespace, objMeta.Name)
+		return nil, fmt.Errorf("Generic AI deployment %s:%s: Specify ports in AIDeployment.Spec.Endpoint not the container", ai.Namespace, ai.Name)
 	}
 
 	expose.Ports = make([]v1.ContainerPort, 0, 1)
-	for _, ep := range l.AIDeployment.Spec.Endpoint {
+	for _, ep := range ai.Spec.Endpoint {
 		expose.Ports = append(expose.Ports, v1.ContainerPort{ContainerPort: ep.Port})
 	}
 
-	mergeProbe(l.AIDeployment.Spec.Deployment.StartupProbe, expose.StartupProbe)
-	mergeProbe(l.AIDeployment.Spec.Deployment.ReadinessProbe, expose.ReadinessProbe)
-	mergeProbe(l.AIDeployment.Spec.Deployment.LivenessProbe, expose.LivenessProbe)
-
-	pod.AutomountServiceAccountToken = &serviceAccount
-
-	deploymentLabels := resources.GenDefaultLabels(l.AIDeployment.Name)
-
-	deployment.Spec.Template.Labels = utils.MergeMaps(
-		deploymentLabels,
-		deployment.Spec.Template.Labels,
-		l.AIDeployment.Spec.Deployment.Labels,
-	)
-
-	deployment.Spec.Template.Annotations = utils.MergeMaps(
-		deployment.Spec.Template.Annotations,
-		l.AIDeployment.Spec.Deployment.Annotations,
-	)
+	// The container comes from the template, so its pull policy is only
+	// replaced if the option is set
+	policy, err := imagePullPolicy(ai)
+	if err != nil {
+		return nil, err
+	}
+	if policy != "" {
+		expose.ImagePullPolicy = policy
+	}
 
-	deployment.ObjectMeta = objMeta
-	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: deploymentLabels}
+	mergeProbe(ai.Spec.Deployment.StartupProbe, expose.StartupProbe)
+	mergeProbe(ai.Spec.Deployment.ReadinessProbe, expose.ReadinessProbe)
+	mergeProbe(ai.Spec.Deployment.LivenessProbe, expose.LivenessProbe)
 
-	return &deployment, nil
+	return deployment, nil
 }
@@ -0,0 +1,21 @@
+package engines
+
+import (
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+)
+
+// EngineNameIndexKey is the field index of AIDeployments by engine name
+const EngineNameIndexKey = ".spec.engine.name"
+
+// IndexEngineName extracts the engine name of an AIDeployment, it is meant
+// to be passed to a FieldIndexer
+func IndexEngineName(obj ctrlClient.Object) []string {
+	d, ok := obj.(*a1.AIDeployment)
+	if !ok {
+		return nil
+	}
+
+	return []string{string(d.Spec.Engine.Name)}
+}
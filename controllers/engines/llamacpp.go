@@ -0,0 +1,213 @@
+package engines
+
+import (
+	"fmt"
+	"strconv"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aideployment"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/pkg/downloader"
+	"github.com/premAI-io/prem-operator/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	llamacppPort         = int32(8080)
+	llamacppModelsVolume = "models"
+	llamacppModelsPath   = "/models"
+	llamacppModelFile    = "model.gguf"
+	// Offload every layer when GPUs are requested and gpuLayers isn't set
+	llamacppAllGPULayers = "999"
+)
+
+// Llamacpp runs the llama.cpp HTTP server with a single GGUF model, which is
+// downloaded by an init container or mounted from the AIDeployment's
+// ModelCache
+type Llamacpp struct {
+	AIDeployment *a1.AIDeployment
+	model        aimodelmap.ResolvedModel
+}
+
+func init() {
+	Register(Definition{
+		Name:        a1.AIEngineNameLlamacpp,
+		ModelMapKey: a1.AIModelMapKeyLlamacpp,
+		DefaultPort: llamacppPort,
+		New:         NewLlamacpp,
+		Validate:    validateLlamacpp,
+		ModelCache:  true,
+		// The URI is always of a GGUF file
+		ModelCacheFile: func(aimodelmap.ResolvedModel) bool { return true },
+	})
+}
+
+func validateLlamacpp(ai *a1.AIDeployment, models []aimodelmap.ResolvedModel) error {
+	if err := singleModel(ai, models); err != nil {
+		return err
+	}
+
+	if uri := models[0].Spec.Uri; !downloader.Supported(uri) || downloader.IsDirectory(uri) {
+		return fmt.Errorf("llama.cpp model URI must be the URL of a GGUF file")
+	}
+
+	return nil
+}
+
+func NewLlamacpp(ai *a1.AIDeployment, models []aimodelmap.ResolvedModel) (aideployment.MLEngine, error) {
+	if err := validateLlamacpp(ai, models); err != nil {
+		return nil, err
+	}
+
+	return &Llamacpp{AIDeployment: ai, model: models[0]}, nil
+}
+
+func (l *Llamacpp) Port() int32 {
+	return llamacppPort
+}
+
+func (l *Llamacpp) args(gpus int64) ([]string, error) {
+	args := []string{
+		"--host", "0.0.0.0",
+		"--port", strconv.Itoa(int(l.Port())),
+		"--model", fmt.Sprintf("%s/%s", llamacppModelsPath, llamacppModelFile),
+		"--alias", l.model.Name,
+	}
+
+	engineOpts := make(map[string]string)
+	if l.model.Spec.ContextSize > 0 {
+		engineOpts[constants.ContextSizeKey] = strconv.Itoa(int(l.model.Spec.ContextSize))
+	}
+	if gpus > 0 {
+		engineOpts[constants.GPULayersKey] = llamacppAllGPULayers
+	}
+	opts := utils.MergeMaps(engineOpts, l.AIDeployment.Spec.Engine.Options)
+
+	for _, o := range []struct {
+		key  string
+		flag string
+	}{
+		{constants.ContextSizeKey, "--ctx-size"},
+		{constants.ThreadsKey, "--threads"},
+		{constants.GPULayersKey, "--n-gpu-layers"},
+	} {
+		val, ok := opts[o.key]
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(val); err != nil || n < 0 {
+			return nil, fmt.Errorf("%s must be a non-negative integer", o.key)
+		}
+		args = append(args, o.flag, val)
+	}
+
+	return args, nil
+}
+
+func (l *Llamacpp) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
+	gpus, err := aideployment.NeededGPUs(l.AIDeployment.Spec.Deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := l.args(gpus.Value())
+	if err != nil {
+		return nil, err
+	}
+
+	imageTag := constants.ImageTagLlamacppDefault
+	if gpus.Value() > 0 {
+		imageTag = constants.ImageTagLlamacppCuda
+	}
+	if l.AIDeployment.Spec.Engine.Options[constants.ImageTagKey] != "" {
+		imageTag = l.AIDeployment.Spec.Engine.Options[constants.ImageTagKey]
+	}
+	imageRepo := constants.ImageRepositoryLlamacpp
+	if l.AIDeployment.Spec.Engine.Options[constants.ImageRepositoryKey] != "" {
+		imageRepo = l.AIDeployment.Spec.Engine.Options[constants.ImageRepositoryKey]
+	}
+
+	modelFile := fmt.Sprintf("%s/%s", llamacppModelsPath, llamacppModelFile)
+	modelsMount := v1.VolumeMount{
+		Name:      llamacppModelsVolume,
+		MountPath: llamacppModelsPath,
+	}
+	modelsVolume := v1.Volume{
+		Name: llamacppModelsVolume,
+		VolumeSource: v1.VolumeSource{
+			EmptyDir: &v1.EmptyDirVolumeSource{},
+		},
+	}
+
+	initContainers := []v1.Container{}
+	if !cached(l.AIDeployment, l.model) {
+		initContainers = append(initContainers, downloadContainer(
+			l.AIDeployment.Spec.Env,
+			fmt.Sprintf("init-models-%s", l.AIDeployment.Name),
+			l.model.Spec,
+			modelFile,
+			false,
+			modelsMount,
+		))
+	}
+
+	healthProbeHandler := v1.ProbeHandler{
+		HTTPGet: &v1.HTTPGetAction{
+			Path: "/health",
+			Port: intstr.FromInt(int(l.Port())),
+		},
+	}
+
+	container := v1.Container{
+		ImagePullPolicy: v1.PullAlways,
+		Name:            constants.ContainerEngineName,
+		Image:           fmt.Sprintf("%s:%s", imageRepo, imageTag),
+		Env:             l.AIDeployment.Spec.Env,
+		Args:            args,
+		Ports: []v1.ContainerPort{
+			{ContainerPort: l.Port(), Name: "http", Protocol: v1.ProtocolTCP},
+		},
+		VolumeMounts: []v1.VolumeMount{modelsMount},
+		StartupProbe: &v1.Probe{
+			InitialDelaySeconds: 1,
+			PeriodSeconds:       5,
+			FailureThreshold:    120,
+			ProbeHandler:        healthProbeHandler,
+		},
+		ReadinessProbe: &v1.Probe{
+			FailureThreshold: 3,
+			ProbeHandler:     healthProbeHandler,
+		},
+		LivenessProbe: &v1.Probe{
+			PeriodSeconds:    30,
+			TimeoutSeconds:   15,
+			FailureThreshold: 10,
+			ProbeHandler:     healthProbeHandler,
+		},
+	}
+
+	mergeProbe(l.AIDeployment.Spec.Deployment.StartupProbe, container.StartupProbe)
+	mergeProbe(l.AIDeployment.Spec.Deployment.ReadinessProbe, container.ReadinessProbe)
+	mergeProbe(l.AIDeployment.Spec.Deployment.LivenessProbe, container.LivenessProbe)
+
+	deployment := newDeployment(l.AIDeployment, owner)
+	pod := &deployment.Spec.Template.Spec
+
+	// A cached model is mounted where it would be downloaded to
+	if cached(l.AIDeployment, l.model) {
+		addModelCacheVolume(l.AIDeployment, pod)
+		container.VolumeMounts = []v1.VolumeMount{cachedModelMount(l.model, true, modelFile)}
+	} else {
+		pod.Volumes = append(pod.Volumes, modelsVolume)
+	}
+
+	if err := addEngineContainers(l.AIDeployment, pod, container, initContainers...); err != nil {
+		return nil, err
+	}
+
+	return deployment, nil
+}
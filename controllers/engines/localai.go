@@ -2,41 +2,84 @@ package engines
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/premAI-io/prem-operator/controllers/aideployment"
 	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
 	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/pkg/downloader"
 	"github.com/premAI-io/prem-operator/pkg/utils"
 
 	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
-	"github.com/premAI-io/prem-operator/controllers/resources"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
 )
 
+const localAIPort = int32(8080)
+
+// localAIModelConfig is the subset of a LocalAI model config which is
+// generated from the fields of AIModelSpec
+type localAIModelConfig struct {
+	Name         string                   `json:"name"`
+	Backend      string                   `json:"backend,omitempty"`
+	ContextSize  int32                    `json:"context_size,omitempty"`
+	F16          *bool                    `json:"f16,omitempty"`
+	GPULayers    *int                     `json:"gpu_layers,omitempty"`
+	Threads      *int                     `json:"threads,omitempty"`
+	Quantization string                   `json:"quantization,omitempty"`
+	Parameters   localAIModelParameters   `json:"parameters"`
+	Template     *localAIModelTemplateSet `json:"template,omitempty"`
+}
+
+type localAIModelParameters struct {
+	Model string `json:"model"`
+}
+
+type localAIModelTemplateSet struct {
+	Chat        string `json:"chat,omitempty"`
+	ChatMessage string `json:"chat_message,omitempty"`
+	Completion  string `json:"completion,omitempty"`
+	Function    string `json:"function,omitempty"`
+}
+
 type LocalAI struct {
 	AIDeployment *a1.AIDeployment
 	Models       []aimodelmap.ResolvedModel
 }
 
+func init() {
+	Register(Definition{
+		Name:        a1.AIEngineNameLocalai,
+		ModelMapKey: a1.AIModelMapKeyLocalai,
+		DefaultPort: localAIPort,
+		New: func(ai *a1.AIDeployment, m []aimodelmap.ResolvedModel) (aideployment.MLEngine, error) {
+			return NewLocalAI(ai, m), nil
+		},
+		ValidateEngineConfig: validateLocalAIConfig,
+		ModelCache:           true,
+		ModelCacheFile:       localAIModelFile,
+	})
+}
+
+// localAIModelFile is true if m is a single model file, which it is unless
+// its URI is a directory
+func localAIModelFile(m aimodelmap.ResolvedModel) bool {
+	return !downloader.IsDirectory(m.Spec.Uri)
+}
+
 func NewLocalAI(ai *a1.AIDeployment, m []aimodelmap.ResolvedModel) aideployment.MLEngine {
 	return &LocalAI{AIDeployment: ai, Models: m}
 
 }
 func (l *LocalAI) Port() int32 {
-	return 8080
+	return localAIPort
 }
 
 func (l *LocalAI) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
-	objMeta := metav1.ObjectMeta{
-		Name:            l.AIDeployment.Name,
-		Namespace:       l.AIDeployment.Namespace,
-		OwnerReferences: resources.GenOwner(owner),
-	}
-
 	imageTag := constants.ImageTagLatest
 	if l.AIDeployment.Spec.Engine.Options[constants.ImageTagKey] != "" {
 		imageTag = l.AIDeployment.Spec.Engine.Options[constants.ImageTagKey]
@@ -47,17 +90,9 @@ func (l *LocalAI) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
 		imageRepository = l.AIDeployment.Spec.Engine.Options[constants.ImageRepositoryKey]
 	}
 
-	deployment := appsv1.Deployment{}
-	if l.AIDeployment.Spec.Deployment.PodTemplate != nil {
-		deployment.Spec.Template = *l.AIDeployment.Spec.Deployment.PodTemplate.DeepCopy()
-	} else {
-		deployment.Spec.Template = v1.PodTemplateSpec{}
-	}
-	deployment.Spec.Replicas = l.AIDeployment.Spec.Deployment.Replicas
+	deployment := newDeployment(l.AIDeployment, owner)
 	pod := &deployment.Spec.Template.Spec
 
-	serviceAccount := false
-
 	v := l.AIDeployment.Spec.Env
 
 	v = append(v, v1.EnvVar{Name: "MODELS_PATH", Value: "/models"})
@@ -107,8 +142,6 @@ func (l *LocalAI) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
 	mergeProbe(l.AIDeployment.Spec.Deployment.ReadinessProbe, expose.ReadinessProbe)
 	mergeProbe(l.AIDeployment.Spec.Deployment.LivenessProbe, expose.LivenessProbe)
 
-	pod.AutomountServiceAccountToken = &serviceAccount
-
 	pod.Volumes = append(pod.Volumes, v1.Volume{
 		Name: "models",
 		VolumeSource: v1.VolumeSource{
@@ -123,77 +156,89 @@ func (l *LocalAI) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
 		},
 	})
 
-	configSources := []v1.VolumeProjection{}
-	configSourceVolume := "configs"
+	gpus, err := aideployment.NeededGPUs(l.AIDeployment.Spec.Deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	configInit := v1.Container{
+		ImagePullPolicy: v1.PullAlways,
+		Name:            "init-model-configs",
+		Image:           image,
+		Command:         []string{"sh", "-c"},
+		VolumeMounts: []v1.VolumeMount{
+			{
+				Name:      "models",
+				MountPath: "/models",
+			},
+		},
+	}
+	configScript := []string{"set -e"}
+	initContainers := []v1.Container{}
 
 	for _, m := range l.Models {
+		// If an engine config is set then specifying the model some other way doesn't make sense
 		if m.Spec.EngineConfigFile != "" {
-			if m.Variant == "" {
-				return nil, fmt.Errorf("inline model %s has engine config file, but we haven't implemented generating ConfigMaps for inline configs", m.Name)
-			}
-			configSources = append(configSources, v1.VolumeProjection{
-				ConfigMap: &v1.ConfigMapProjection{
-					LocalObjectReference: v1.LocalObjectReference{
-						Name: m.Name,
-					},
-					Items: []v1.KeyToPath{
-						{
-							Key:  aimodelmap.FmtConfigMapKey(a1.AIEngineNameLocalai, m.Variant, constants.AIModelMapSpecEngineConfig),
-							Path: "engine/" + m.HostName + ".yaml",
-						},
-					},
-				},
-			})
-
-			// If an engine config is set then specifying the model some other way doesn't make sense
 			continue
 		}
 
-		if strings.HasPrefix(m.Spec.Uri, "http") {
-			pod.InitContainers = append(pod.InitContainers, v1.Container{
-				ImagePullPolicy: v1.PullAlways,
-				Name:            fmt.Sprintf("init-models-%s", l.AIDeployment.Name),
-				Image:           image,
-				Command:         []string{"sh", "-c"},
-				Args:            []string{"curl -L -v -o /models/$MODEL_NAME $MODEL_PATH"},
-				Env: []v1.EnvVar{
-					{Name: "MODEL_NAME", Value: m.Name},
-					{Name: "MODEL_PATH", Value: m.Spec.Uri},
-				},
-				VolumeMounts: []v1.VolumeMount{
-					{
-						Name:      "models",
-						MountPath: "/models",
-					},
-				},
-			})
-		} else {
+		config, err := l.modelConfig(m, gpus.Value())
+		if err != nil {
+			return nil, err
+		}
+
+		if config != "" {
+			env := fmt.Sprintf("MODEL_CONFIG_%d", len(configInit.Env))
+			configInit.Env = append(configInit.Env, v1.EnvVar{Name: env, Value: config})
+			configScript = append(configScript, fmt.Sprintf(`printf '%%s' "$%s" > /models/%s.yaml`, env, m.HostName))
+		}
+
+		if cached(l.AIDeployment, m) {
+			// The model is mounted where it would be downloaded to
+			addModelCacheVolume(l.AIDeployment, pod)
+			expose.VolumeMounts = append(expose.VolumeMounts,
+				cachedModelMount(m, localAIModelFile(m), "/models/"+m.Name))
+		} else if downloader.Supported(m.Spec.Uri) {
+			initContainers = append(initContainers, downloadContainer(
+				l.AIDeployment.Spec.Env,
+				fmt.Sprintf("init-models-%s-%d", l.AIDeployment.Name, len(initContainers)),
+				m.Spec,
+				"/models/"+m.Name,
+				false,
+				v1.VolumeMount{Name: "models", MountPath: "/models"},
+			))
+		} else if config == "" {
 			// Pass models as args.
 			// LocalAI accepts both names and full URLs passed by as Args.
 			expose.Args = append(expose.Args, m.Spec.Uri)
 		}
 	}
 
-	if len(configSources) > 0 {
-		pod.Volumes = append(pod.Volumes, v1.Volume{
-			Name: configSourceVolume,
-			VolumeSource: v1.VolumeSource{
-				Projected: &v1.ProjectedVolumeSource{
-					Sources: configSources,
-				},
-			},
-		})
+	if len(configInit.Env) > 0 {
+		configInit.Args = []string{strings.Join(configScript, "\n")}
+		initContainers = append(initContainers, configInit)
+	}
+
+	configVolume, err := engineConfigVolume(a1.AIEngineNameLocalai, l.Models, ".yaml")
+	if err != nil {
+		return nil, err
+	}
 
-		pod.InitContainers = append(pod.InitContainers, v1.Container{
+	if configVolume != nil {
+		pod.Volumes = append(pod.Volumes, *configVolume)
+
+		initContainers = append(initContainers, v1.Container{
 			ImagePullPolicy: v1.PullAlways,
-			Name:            fmt.Sprintf("init-%s-%s", configSourceVolume, l.AIDeployment.Name),
+			Name:            fmt.Sprintf("init-%s-%s", engineConfigVolumeName, l.AIDeployment.Name),
 			Image:           image,
 			Command:         []string{"sh", "-c"},
-			Args:            []string{fmt.Sprintf("ls /configs/engine && cp -v /%s/engine/* /models", configSourceVolume)},
+			Args: []string{fmt.Sprintf(
+				"ls %[1]s/%[2]s && cp -v %[1]s/%[2]s/* /models", engineConfigMountPath, engineConfigDir,
+			)},
 			VolumeMounts: []v1.VolumeMount{
 				{
-					Name:      configSourceVolume,
-					MountPath: "/" + configSourceVolume,
+					Name:      engineConfigVolumeName,
+					MountPath: engineConfigMountPath,
 				},
 				{
 					Name:      "models",
@@ -203,21 +248,103 @@ func (l *LocalAI) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
 		})
 	}
 
-	pod.Containers = append(pod.Containers, *expose)
-	deploymentLabels := resources.GenDefaultLabels(l.AIDeployment.Name)
-	deployment.Spec.Template.Labels = utils.MergeMaps(
-		deploymentLabels,
-		deployment.Spec.Template.Labels,
-		l.AIDeployment.Spec.Deployment.Labels,
-	)
+	if err := addEngineContainers(l.AIDeployment, pod, *expose, initContainers...); err != nil {
+		return nil, err
+	}
+
+	return deployment, nil
+}
+
+// modelConfig generates a LocalAI model config from the fields of the model
+// spec and the engine options. It returns an empty string if none are set, in
+// which case LocalAI is left to configure the model itself.
+func (l *LocalAI) modelConfig(m aimodelmap.ResolvedModel, gpus int64) (string, error) {
+	opts := l.AIDeployment.Spec.Engine.Options
+	_, hasGPULayers := opts[constants.GPULayersKey]
+	_, hasThreads := opts[constants.ThreadsKey]
 
-	deployment.Spec.Template.Annotations = utils.MergeMaps(
-		deployment.Spec.Template.Annotations,
-		l.AIDeployment.Spec.Deployment.Annotations,
-	)
+	if m.Spec.Backend == "" && m.Spec.ContextSize == 0 && m.Spec.DataType == "" &&
+		m.Spec.Quantization == "" && m.Spec.Templates == nil && !hasGPULayers && !hasThreads {
+		return "", nil
+	}
+
+	config := localAIModelConfig{
+		Name:         m.HostName,
+		Backend:      m.Spec.Backend,
+		ContextSize:  m.Spec.ContextSize,
+		Quantization: string(m.Spec.Quantization),
+		Parameters:   localAIModelParameters{Model: m.Spec.Uri},
+	}
+
+	// Downloaded models are in /models under the model's name
+	if downloader.Supported(m.Spec.Uri) {
+		config.Parameters.Model = m.Name
+	}
+
+	if m.Spec.DataType != "" {
+		f16 := m.Spec.DataType == a1.AIModelDataTypeFloat16 || m.Spec.DataType == a1.AIModelDataTypeBFloat16
+		config.F16 = &f16
+	}
+
+	engineOpts := make(map[string]string)
+	if gpus > 0 {
+		engineOpts[constants.GPULayersKey] = llamacppAllGPULayers
+	}
+	opts = utils.MergeMaps(engineOpts, opts)
 
-	deployment.ObjectMeta = objMeta
-	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: deploymentLabels}
+	for _, o := range []struct {
+		key string
+		dst **int
+	}{
+		{constants.GPULayersKey, &config.GPULayers},
+		{constants.ThreadsKey, &config.Threads},
+	} {
+		val, ok := opts[o.key]
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("%s must be a non-negative integer", o.key)
+		}
+		*o.dst = &n
+	}
+
+	if t := m.Spec.Templates; t != nil {
+		config.Template = &localAIModelTemplateSet{
+			Chat:        t.Chat,
+			ChatMessage: t.ChatMessage,
+			Completion:  t.Completion,
+			Function:    t.Function,
+		}
+	}
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// validateLocalAIConfig checks the config is a single LocalAI model
+// definition. Only the fields LocalAI can't do without are checked.
+func validateLocalAIConfig(config string) error {
+	var model map[string]interface{}
+	if err := yaml.Unmarshal([]byte(config), &model); err != nil {
+		return fmt.Errorf("LocalAI model config must be a YAML mapping: %w", err)
+	}
+
+	name, ok := model["name"].(string)
+	if !ok || name == "" {
+		return fmt.Errorf("LocalAI model config must have a name")
+	}
+
+	if params, ok := model["parameters"]; ok {
+		if _, ok := params.(map[string]interface{}); !ok {
+			return fmt.Errorf("LocalAI model config parameters must be a mapping")
+		}
+	}
 
-	return &deployment, nil
+	return nil
 }
@@ -0,0 +1,185 @@
+package engines
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aideployment"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	ollamaPort            = int32(11434)
+	ollamaModelsVolume    = "models"
+	ollamaModelsPath      = "/root/.ollama"
+	ollamaModelfileSuffix = ".Modelfile"
+)
+
+// ollamaInitScript starts a private Ollama server then pulls the models given
+// as arguments and creates a model from each Modelfile
+var ollamaInitScript = fmt.Sprintf(`set -e
+ollama serve &
+server=$!
+until ollama list >/dev/null 2>&1; do sleep 1; done
+for m in "$@"; do ollama pull "$m"; done
+for f in %[1]s/%[2]s/*%[3]s; do
+  [ -e "$f" ] || continue
+  ollama create "$(basename "$f" %[3]s)" -f "$f"
+done
+kill $server`, engineConfigMountPath, engineConfigDir, ollamaModelfileSuffix)
+
+// Ollama pulls models with the Ollama CLI before starting the server. Models
+// with an engine config are created from it as a Modelfile, their name is the
+// model's HostName. Other models are pulled using their URI as the tag.
+type Ollama struct {
+	AIDeployment *a1.AIDeployment
+	Models       []aimodelmap.ResolvedModel
+}
+
+func init() {
+	Register(Definition{
+		Name:        a1.AIEngineNameOllama,
+		ModelMapKey: a1.AIModelMapKeyOllama,
+		DefaultPort: ollamaPort,
+		New: func(ai *a1.AIDeployment, m []aimodelmap.ResolvedModel) (aideployment.MLEngine, error) {
+			return NewOllama(ai, m), nil
+		},
+		ValidateEngineConfig: validateModelfile,
+	})
+}
+
+func NewOllama(ai *a1.AIDeployment, m []aimodelmap.ResolvedModel) aideployment.MLEngine {
+	return &Ollama{AIDeployment: ai, Models: m}
+}
+
+func (o *Ollama) Port() int32 {
+	return ollamaPort
+}
+
+func (o *Ollama) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
+	imageTag := constants.ImageTagLatest
+	if o.AIDeployment.Spec.Engine.Options[constants.ImageTagKey] != "" {
+		imageTag = o.AIDeployment.Spec.Engine.Options[constants.ImageTagKey]
+	}
+	imageRepo := constants.ImageRepositoryOllama
+	if o.AIDeployment.Spec.Engine.Options[constants.ImageRepositoryKey] != "" {
+		imageRepo = o.AIDeployment.Spec.Engine.Options[constants.ImageRepositoryKey]
+	}
+	image := fmt.Sprintf("%s:%s", imageRepo, imageTag)
+
+	deployment := newDeployment(o.AIDeployment, owner)
+	pod := &deployment.Spec.Template.Spec
+
+	modelsMount := v1.VolumeMount{
+		Name:      ollamaModelsVolume,
+		MountPath: ollamaModelsPath,
+	}
+	pod.Volumes = append(pod.Volumes, v1.Volume{
+		Name: ollamaModelsVolume,
+		VolumeSource: v1.VolumeSource{
+			EmptyDir: &v1.EmptyDirVolumeSource{},
+		},
+	})
+
+	pulls := []string{}
+	for _, m := range o.Models {
+		if m.Spec.EngineConfigFile == "" {
+			pulls = append(pulls, m.Spec.Uri)
+		}
+	}
+
+	initContainer := v1.Container{
+		ImagePullPolicy: v1.PullAlways,
+		Name:            fmt.Sprintf("init-models-%s", o.AIDeployment.Name),
+		Image:           image,
+		Command:         append([]string{"sh", "-c", ollamaInitScript, "ollama-init"}, pulls...),
+		Env: []v1.EnvVar{
+			{Name: "OLLAMA_HOST", Value: "127.0.0.1"},
+		},
+		VolumeMounts: []v1.VolumeMount{modelsMount},
+	}
+
+	configVolume, err := engineConfigVolume(a1.AIEngineNameOllama, o.Models, ollamaModelfileSuffix)
+	if err != nil {
+		return nil, err
+	}
+	if configVolume != nil {
+		pod.Volumes = append(pod.Volumes, *configVolume)
+		initContainer.VolumeMounts = append(initContainer.VolumeMounts, v1.VolumeMount{
+			Name:      engineConfigVolumeName,
+			MountPath: engineConfigMountPath,
+		})
+	}
+
+	initContainers := []v1.Container{}
+	if len(pulls) > 0 || configVolume != nil {
+		initContainers = append(initContainers, initContainer)
+	}
+
+	healthProbeHandler := v1.ProbeHandler{
+		HTTPGet: &v1.HTTPGetAction{
+			Path: "/api/version",
+			Port: intstr.FromInt(int(o.Port())),
+		},
+	}
+
+	container := v1.Container{
+		ImagePullPolicy: v1.PullAlways,
+		Name:            constants.ContainerEngineName,
+		Image:           image,
+		Env: append([]v1.EnvVar{
+			{Name: "OLLAMA_HOST", Value: fmt.Sprintf("0.0.0.0:%d", o.Port())},
+		}, o.AIDeployment.Spec.Env...),
+		Ports: []v1.ContainerPort{
+			{ContainerPort: o.Port(), Name: "http", Protocol: v1.ProtocolTCP},
+		},
+		VolumeMounts: []v1.VolumeMount{modelsMount},
+		StartupProbe: &v1.Probe{
+			InitialDelaySeconds: 1,
+			PeriodSeconds:       5,
+			FailureThreshold:    60,
+			ProbeHandler:        healthProbeHandler,
+		},
+		ReadinessProbe: &v1.Probe{
+			FailureThreshold: 3,
+			ProbeHandler:     healthProbeHandler,
+		},
+		LivenessProbe: &v1.Probe{
+			PeriodSeconds:    30,
+			TimeoutSeconds:   15,
+			FailureThreshold: 10,
+			ProbeHandler:     healthProbeHandler,
+		},
+	}
+
+	mergeProbe(o.AIDeployment.Spec.Deployment.StartupProbe, container.StartupProbe)
+	mergeProbe(o.AIDeployment.Spec.Deployment.ReadinessProbe, container.ReadinessProbe)
+	mergeProbe(o.AIDeployment.Spec.Deployment.LivenessProbe, container.LivenessProbe)
+
+	if err := addEngineContainers(o.AIDeployment, pod, container, initContainers...); err != nil {
+		return nil, err
+	}
+
+	return deployment, nil
+}
+
+// validateModelfile checks the config is an Ollama Modelfile, which must
+// have a FROM instruction
+func validateModelfile(config string) error {
+	scanner := bufio.NewScanner(strings.NewReader(config))
+	for scanner.Scan() {
+		instruction, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if strings.EqualFold(instruction, "FROM") {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Ollama Modelfile must have a FROM instruction")
+}
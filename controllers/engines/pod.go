@@ -0,0 +1,104 @@
+package engines
+
+import (
+	"fmt"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/resources"
+	"github.com/premAI-io/prem-operator/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newDeployment returns the Deployment which an engine adds its containers
+// to. The pod starts from spec.deployment.podTemplate, so the scheduling
+// settings, volumes, sidecars and image pull secrets set there are kept
+// whichever engine is used.
+func newDeployment(ai *a1.AIDeployment, owner metav1.Object) *appsv1.Deployment {
+	deploymentLabels := resources.GenDefaultLabels(ai.Name)
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            ai.Name,
+			Namespace:       ai.Namespace,
+			OwnerReferences: resources.GenOwner(owner),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ai.Spec.Deployment.Replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: deploymentLabels,
+			},
+		},
+	}
+
+	if ai.Spec.Deployment.PodTemplate != nil {
+		deployment.Spec.Template = *ai.Spec.Deployment.PodTemplate.DeepCopy()
+	}
+
+	template := &deployment.Spec.Template
+	template.Labels = utils.MergeMaps(
+		deploymentLabels,
+		template.Labels,
+		ai.Spec.Deployment.Labels,
+	)
+	template.Annotations = utils.MergeMaps(
+		template.Annotations,
+		ai.Spec.Deployment.Annotations,
+	)
+
+	if template.Spec.AutomountServiceAccountToken == nil {
+		serviceAccount := false
+		template.Spec.AutomountServiceAccountToken = &serviceAccount
+	}
+
+	return deployment
+}
+
+// addEngineContainers adds the engine's container and init containers to the
+// pod after those from the pod template. The imagePullPolicy option, if set,
+// replaces the engine's pull policies.
+func addEngineContainers(ai *a1.AIDeployment, pod *v1.PodSpec, container v1.Container, initContainers ...v1.Container) error {
+	policy, err := imagePullPolicy(ai)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range pod.Containers {
+		if c.Name == container.Name {
+			return fmt.Errorf("the pod template can't have a container named %s, it is the engine's", c.Name)
+		}
+	}
+
+	for _, c := range initContainers {
+		if policy != "" {
+			c.ImagePullPolicy = policy
+		}
+		pod.InitContainers = append(pod.InitContainers, c)
+	}
+
+	if policy != "" {
+		container.ImagePullPolicy = policy
+	}
+	pod.Containers = append(pod.Containers, container)
+
+	return nil
+}
+
+// imagePullPolicy returns the value of the imagePullPolicy option or an empty
+// string if it isn't set
+func imagePullPolicy(ai *a1.AIDeployment) (v1.PullPolicy, error) {
+	policy, ok := ai.Spec.Engine.Options[constants.ImagePullPolicyKey]
+	if !ok {
+		return "", nil
+	}
+
+	switch p := v1.PullPolicy(policy); p {
+	case v1.PullAlways, v1.PullIfNotPresent, v1.PullNever:
+		return p, nil
+	default:
+		return "", fmt.Errorf("%s must be one of %s, %s or %s",
+			constants.ImagePullPolicyKey, v1.PullAlways, v1.PullIfNotPresent, v1.PullNever)
+	}
+}
@@ -0,0 +1,153 @@
+package engines
+
+import (
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/modelcache"
+	"github.com/premAI-io/prem-operator/pkg/downloader"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	prefetchVolumeName = "model-prefetch"
+	// The name of a prefetch init container is this followed by the
+	// model's key
+	prefetchContainerPrefix = "download-"
+	// The weight of the preference for the nodes which have a model, the
+	// highest there is
+	prefetchAffinityWeight = 100
+)
+
+// prefetched is true if m is loaded from the node it was prefetched onto
+func prefetched(m aimodelmap.ResolvedModel) bool {
+	return m.Prefetched && modelcache.Cacheable(m)
+}
+
+func prefetchVolume(path string, hostPathType v1.HostPathType) v1.Volume {
+	return v1.Volume{
+		Name: prefetchVolumeName,
+		VolumeSource: v1.VolumeSource{
+			HostPath: &v1.HostPathVolumeSource{
+				Path: path,
+				Type: &hostPathType,
+			},
+		},
+	}
+}
+
+// PrefetchContainerName is the name of the init container which downloads m
+// in the pods of an AIModelPrefetch
+func PrefetchContainerName(m aimodelmap.ResolvedModel) string {
+	return prefetchContainerPrefix + modelcache.Key(m)
+}
+
+// PrefetchDaemonSet creates the DaemonSet which downloads the models of p
+// onto the nodes it selects. Each model is downloaded by an init container,
+// in the same way addModelDownload would download it into the pod, after
+// which the pod only waits so that nodes which join later get the models as
+// well. The pod runs as the downloader's non-root user without privileges.
+// The directory on the node isn't created, it must exist and be writable by
+// that user on the nodes p selects.
+func PrefetchDaemonSet(p *a1.AIModelPrefetch, models []aimodelmap.ResolvedModel) *appsv1.DaemonSet {
+	labels := map[string]string{
+		constants.ModelPrefetchLabel: p.Name,
+	}
+
+	mount := v1.VolumeMount{
+		Name:      prefetchVolumeName,
+		MountPath: modelcache.PrefetchPath,
+	}
+
+	noPrivilegeEscalation := false
+	securityContext := &v1.SecurityContext{
+		AllowPrivilegeEscalation: &noPrivilegeEscalation,
+		Capabilities: &v1.Capabilities{
+			Drop: []v1.Capability{"ALL"},
+		},
+	}
+
+	initContainers := make([]v1.Container, 0, len(models))
+	for _, m := range models {
+		container := downloadContainer(p.Spec.Env, PrefetchContainerName(m), m.Spec, modelcache.PrefetchDir(m), !downloader.IsDirectory(m.Spec.Uri), mount)
+		container.TerminationMessagePolicy = v1.TerminationMessageFallbackToLogsOnError
+		container.SecurityContext = securityContext
+		initContainers = append(initContainers, container)
+	}
+
+	user := int64(downloaderUser)
+	nonRoot := true
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      p.Name,
+			Namespace: p.Spec.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: downloadPodLabels(labels),
+				},
+				Spec: v1.PodSpec{
+					NodeSelector:   p.Spec.NodeSelector,
+					Tolerations:    p.Spec.Tolerations,
+					InitContainers: initContainers,
+					Containers: []v1.Container{{
+						Name:            "pause",
+						Image:           constants.ImagePause,
+						ImagePullPolicy: v1.PullIfNotPresent,
+						SecurityContext: securityContext,
+					}},
+					SecurityContext: &v1.PodSecurityContext{
+						RunAsUser:    &user,
+						RunAsGroup:   &user,
+						RunAsNonRoot: &nonRoot,
+					},
+					Volumes: []v1.Volume{prefetchVolume(modelcache.PrefetchPath, v1.HostPathDirectory)},
+				},
+			},
+		},
+	}
+}
+
+// addPrefetchedModel lets the pod use the copy of m on its node. Nodes which
+// have m are preferred, but the pod may be scheduled onto any node, so the
+// directory on the node is mounted read-only and the download links to it
+// only if the download in it is finished. Otherwise m is downloaded as usual.
+// The parent of the directory is mounted, and created if it is missing, since
+// a directory created by the kubelet is owned by root and the non-root
+// PrefetchDaemonSet couldn't write to it.
+func addPrefetchedModel(m aimodelmap.ResolvedModel, pod *v1.PodSpec, container *v1.Container, download *v1.Container) {
+	mount := v1.VolumeMount{
+		Name:      prefetchVolumeName,
+		MountPath: modelcache.PrefetchParent,
+		ReadOnly:  true,
+	}
+	pod.Volumes = append(pod.Volumes, prefetchVolume(modelcache.PrefetchParent, v1.HostPathDirectoryOrCreate))
+	container.VolumeMounts = append(container.VolumeMounts, mount)
+	download.VolumeMounts = append(download.VolumeMounts, mount)
+	download.Args = append(download.Args, "--from", modelcache.PrefetchDir(m))
+
+	if pod.Affinity == nil {
+		pod.Affinity = &v1.Affinity{}
+	}
+	if pod.Affinity.NodeAffinity == nil {
+		pod.Affinity.NodeAffinity = &v1.NodeAffinity{}
+	}
+	affinity := pod.Affinity.NodeAffinity
+	affinity.PreferredDuringSchedulingIgnoredDuringExecution = append(affinity.PreferredDuringSchedulingIgnoredDuringExecution, v1.PreferredSchedulingTerm{
+		Weight: prefetchAffinityWeight,
+		Preference: v1.NodeSelectorTerm{
+			MatchExpressions: []v1.NodeSelectorRequirement{{
+				Key:      modelcache.NodeLabel(m),
+				Operator: v1.NodeSelectorOpExists,
+			}},
+		},
+	})
+}
@@ -0,0 +1,91 @@
+package engines_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/engines"
+	"github.com/premAI-io/prem-operator/controllers/modelcache"
+)
+
+var _ = Describe("PrefetchDaemonSet", func() {
+	It("downloads the models as the downloader's user without privileges", func() {
+		p := &a1.AIModelPrefetch{
+			ObjectMeta: metav1.ObjectMeta{Name: "llama"},
+			Spec: a1.AIModelPrefetchSpec{
+				Engine:       a1.AIEngineNameVLLM,
+				Namespace:    "models",
+				NodeSelector: map[string]string{"nvidia.com/gpu": "true"},
+			},
+		}
+		m := model("s3://models/opt-125m/")
+
+		ds := engines.PrefetchDaemonSet(p, []aimodelmap.ResolvedModel{m})
+		Expect(ds.Namespace).To(Equal("models"))
+		Expect(ds.Spec.Selector.MatchLabels).To(HaveKeyWithValue(constants.ModelPrefetchLabel, "llama"))
+		Expect(ds.Spec.Selector.MatchLabels).NotTo(HaveKey(constants.ModelDownloadLabel))
+		Expect(ds.Spec.Template.Labels).To(HaveKeyWithValue(constants.ModelDownloadLabel, "true"))
+
+		pod := ds.Spec.Template.Spec
+		Expect(pod.NodeSelector).To(Equal(p.Spec.NodeSelector))
+		Expect(*pod.SecurityContext.RunAsUser).To(BeEquivalentTo(65532))
+		Expect(*pod.SecurityContext.RunAsNonRoot).To(BeTrue())
+		Expect(pod.Volumes).To(ConsistOf(HaveField("HostPath", And(
+			HaveField("Path", modelcache.PrefetchPath),
+			HaveField("Type", HaveValue(Equal(corev1.HostPathDirectory))),
+		))))
+
+		Expect(pod.InitContainers).To(HaveLen(1))
+		Expect(pod.InitContainers[0].Name).To(Equal(engines.PrefetchContainerName(m)))
+		Expect(pod.InitContainers[0].Args).To(ContainElements("--output", modelcache.PrefetchDir(m)))
+		for _, c := range append(pod.InitContainers, pod.Containers...) {
+			Expect(*c.SecurityContext.AllowPrivilegeEscalation).To(BeFalse())
+			Expect(c.SecurityContext.Capabilities.Drop).To(ConsistOf(corev1.Capability("ALL")))
+		}
+	})
+})
+
+var _ = Describe("Prefetched models", func() {
+	It("prefer the nodes which have the model and fall back to downloading it", func() {
+		m := model("s3://models/opt-125m/")
+		m.Prefetched = true
+
+		d := engineDeployment(a1.AIEngineNameTgi, m, nil)
+		pod := d.Spec.Template.Spec
+
+		Expect(pod.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(BeNil())
+		Expect(pod.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(ConsistOf(
+			HaveField("Preference.MatchExpressions", ConsistOf(corev1.NodeSelectorRequirement{
+				Key:      modelcache.NodeLabel(m),
+				Operator: corev1.NodeSelectorOpExists,
+			})),
+		))
+
+		Expect(pod.Volumes).To(ContainElement(HaveField("HostPath", HaveValue(And(
+			HaveField("Path", modelcache.PrefetchParent),
+			HaveField("Type", HaveValue(Equal(corev1.HostPathDirectoryOrCreate))),
+		)))))
+
+		mount := corev1.VolumeMount{Name: "model-prefetch", MountPath: modelcache.PrefetchParent, ReadOnly: true}
+		Expect(pod.InitContainers).To(HaveLen(1))
+		Expect(pod.InitContainers[0].Args).To(ContainElements("--from", modelcache.PrefetchDir(m)))
+		Expect(pod.InitContainers[0].VolumeMounts).To(ContainElement(mount))
+		Expect(pod.Containers[0].VolumeMounts).To(ContainElement(mount))
+		Expect(argValue(pod.Containers[0].Args, "--model-id")).To(Equal("/downloads/" + m.HostName))
+	})
+
+	It("are loaded from the ModelCache if the AIDeployment has one", func() {
+		m := model("s3://models/opt-125m/")
+		m.Prefetched = true
+
+		d := engineDeployment(a1.AIEngineNameTgi, m, withModelCache)
+		Expect(d.Spec.Template.Spec.InitContainers).To(BeEmpty())
+		Expect(hasVolume(d, "model-prefetch")).To(BeFalse())
+		Expect(argValue(d.Spec.Template.Spec.Containers[0].Args, "--model-id")).To(Equal(modelcache.Dir(m)))
+	})
+})
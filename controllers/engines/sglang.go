@@ -0,0 +1,186 @@
+package engines
+
+import (
+	"fmt"
+	"strconv"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aideployment"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	sglangPort               = int32(30000)
+	sglangCacheVolumePath    = "/root/.cache/huggingface"
+	sglangCacheVolumeName    = "models"
+	sglangDefaultImageTag    = constants.ImageTagLatest
+	sglangLaunchServerModule = "sglang.launch_server"
+)
+
+type Sglang struct {
+	AIDeployment *a1.AIDeployment
+	model        aimodelmap.ResolvedModel
+}
+
+func init() {
+	Register(Definition{
+		Name:          a1.AIEngineNameSglang,
+		ModelMapKey:   a1.AIModelMapKeySglang,
+		DefaultPort:   sglangPort,
+		New:           NewSglang,
+		Validate:      singleModel,
+		ModelCache:    true,
+		ModelPrefetch: true,
+	})
+}
+
+func NewSglang(ai *a1.AIDeployment, models []aimodelmap.ResolvedModel) (aideployment.MLEngine, error) {
+	if err := singleModel(ai, models); err != nil {
+		return nil, err
+	}
+
+	return &Sglang{AIDeployment: ai, model: models[0]}, nil
+}
+
+func (s *Sglang) Port() int32 {
+	return sglangPort
+}
+
+func (s *Sglang) args() ([]string, error) {
+	args := []string{
+		"--model-path", modelPath(s.AIDeployment, s.model),
+		"--host", "0.0.0.0",
+		"--port", strconv.Itoa(int(s.Port())),
+	}
+
+	// Clients refer to a downloaded model by its host name rather than its path
+	if modelPath(s.AIDeployment, s.model) != s.model.Spec.Uri {
+		args = append(args, "--served-model-name", s.model.HostName)
+	}
+
+	engineOpts := make(map[string]string)
+	if s.model.Spec.DataType != "" {
+		engineOpts[constants.DtypeKey] = string(s.model.Spec.DataType)
+	}
+	if s.model.Spec.Quantization != "" {
+		engineOpts[constants.QuantizationKey] = string(s.model.Spec.Quantization)
+	}
+	opts := utils.MergeMaps(engineOpts, s.AIDeployment.Spec.Engine.Options)
+
+	if dtype, ok := opts[constants.DtypeKey]; ok {
+		if !utils.IsAlphanumeric(dtype) {
+			return nil, fmt.Errorf("dtype must be alphanumeric")
+		}
+		args = append(args, "--dtype", dtype)
+	}
+
+	if quant, ok := opts[constants.QuantizationKey]; ok {
+		if !utils.IsAlphanumeric(quant) {
+			return nil, fmt.Errorf("quantization must be alphanumeric")
+		}
+		args = append(args, "--quantization", quant)
+	}
+
+	tp, err := tensorParallelSize(s.AIDeployment)
+	if err != nil {
+		return nil, err
+	}
+	if tp > 1 {
+		args = append(args, "--tp", strconv.FormatInt(tp, 10))
+	}
+
+	return args, nil
+}
+
+func (s *Sglang) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
+	args, err := s.args()
+	if err != nil {
+		return nil, err
+	}
+
+	tp, err := tensorParallelSize(s.AIDeployment)
+	if err != nil {
+		return nil, err
+	}
+
+	imageTag := sglangDefaultImageTag
+	if s.AIDeployment.Spec.Engine.Options[constants.ImageTagKey] != "" {
+		imageTag = s.AIDeployment.Spec.Engine.Options[constants.ImageTagKey]
+	}
+	imageRepo := constants.ImageRepositorySglang
+	if s.AIDeployment.Spec.Engine.Options[constants.ImageRepositoryKey] != "" {
+		imageRepo = s.AIDeployment.Spec.Engine.Options[constants.ImageRepositoryKey]
+	}
+
+	healthProbeHandler := v1.ProbeHandler{
+		HTTPGet: &v1.HTTPGetAction{
+			Path: "/health",
+			Port: intstr.FromInt(int(s.Port())),
+		},
+	}
+
+	container := v1.Container{
+		ImagePullPolicy: v1.PullAlways,
+		Name:            constants.ContainerEngineName,
+		Image:           fmt.Sprintf("%s:%s", imageRepo, imageTag),
+		Command:         []string{"python3", "-m", sglangLaunchServerModule},
+		Env:             hfTokenEnv(s.model.Spec, s.AIDeployment.Spec.Env),
+		Args:            args,
+		Ports: []v1.ContainerPort{
+			{ContainerPort: s.Port(), Name: "http", Protocol: v1.ProtocolTCP},
+		},
+		VolumeMounts: []v1.VolumeMount{
+			{
+				Name:      sglangCacheVolumeName,
+				MountPath: sglangCacheVolumePath,
+			},
+		},
+		StartupProbe: &v1.Probe{
+			InitialDelaySeconds: 3,
+			PeriodSeconds:       5,
+			FailureThreshold:    360,
+			ProbeHandler:        healthProbeHandler,
+		},
+		ReadinessProbe: &v1.Probe{
+			FailureThreshold: 3,
+			ProbeHandler:     healthProbeHandler,
+		},
+		LivenessProbe: &v1.Probe{
+			PeriodSeconds:    30,
+			TimeoutSeconds:   15,
+			FailureThreshold: 10,
+			ProbeHandler:     healthProbeHandler,
+		},
+	}
+
+	mergeProbe(s.AIDeployment.Spec.Deployment.StartupProbe, container.StartupProbe)
+	mergeProbe(s.AIDeployment.Spec.Deployment.ReadinessProbe, container.ReadinessProbe)
+	mergeProbe(s.AIDeployment.Spec.Deployment.LivenessProbe, container.LivenessProbe)
+
+	deployment := newDeployment(s.AIDeployment, owner)
+	pod := &deployment.Spec.Template.Spec
+	pod.Volumes = append(pod.Volumes, v1.Volume{
+		Name: sglangCacheVolumeName,
+		VolumeSource: v1.VolumeSource{
+			EmptyDir: &v1.EmptyDirVolumeSource{},
+		},
+	})
+
+	if err := addSharedMemory(s.AIDeployment, tp, pod, &container); err != nil {
+		return nil, err
+	}
+
+	initContainers := addModelDownload(s.AIDeployment, s.model, pod, &container)
+
+	if err := addEngineContainers(s.AIDeployment, pod, container, initContainers...); err != nil {
+		return nil, err
+	}
+
+	return deployment, nil
+}
@@ -0,0 +1,75 @@
+package engines_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/modelcache"
+)
+
+var _ = Describe("Sglang", func() {
+	DescribeTable("splits the model across GPUs",
+		func(mutate func(*a1.AIDeployment), tp string) {
+			d := engineDeployment(a1.AIEngineNameSglang, model("facebook/opt-125m"), mutate)
+			c := d.Spec.Template.Spec.Containers[0]
+
+			Expect(argValue(c.Args, "--tp")).To(Equal(tp))
+			Expect(hasVolume(d, "dshm")).To(Equal(tp != ""))
+			if tp != "" {
+				Expect(c.VolumeMounts).To(ContainElement(HaveField("MountPath", "/dev/shm")))
+			}
+		},
+		Entry("without GPUs", nil, ""),
+		Entry("with one GPU", withGPUs(1), ""),
+		Entry("with two GPUs", withGPUs(2), "2"),
+		Entry("with the tensor parallel size option", func(ai *a1.AIDeployment) {
+			withGPUs(8)(ai)
+			ai.Spec.Engine.Options = map[string]string{constants.TensorParallelSizeKey: "4"}
+		}, "4"),
+	)
+
+	DescribeTable("loads the model from",
+		func(uri string, mutate func(*a1.AIDeployment), path func(aimodelmap.ResolvedModel) string, servedName bool) {
+			m := model(uri)
+			d := engineDeployment(a1.AIEngineNameSglang, m, mutate)
+			args := d.Spec.Template.Spec.Containers[0].Args
+
+			Expect(argValue(args, "--model-path")).To(Equal(path(m)))
+			if servedName {
+				Expect(argValue(args, "--served-model-name")).To(Equal(m.HostName))
+			} else {
+				Expect(args).NotTo(ContainElement("--served-model-name"))
+			}
+		},
+		Entry("the Hugging Face Hub", "facebook/opt-125m", nil,
+			func(m aimodelmap.ResolvedModel) string { return m.Spec.Uri }, false),
+		Entry("the downloads volume", "s3://models/opt-125m/", nil,
+			func(m aimodelmap.ResolvedModel) string { return "/downloads/" + m.HostName }, true),
+		Entry("a ModelCache", "s3://models/opt-125m/", withModelCache, modelcache.Dir, true),
+	)
+
+	It("passes the dtype and quantization", func() {
+		m := model("TheBloke/TinyLlama-1.1B-Chat-v1.0-AWQ")
+		m.Spec.DataType = a1.AIModelDataTypeFloat16
+		m.Spec.Quantization = a1.AIModelQuantizationAWQ
+
+		d := engineDeployment(a1.AIEngineNameSglang, m, nil)
+		args := d.Spec.Template.Spec.Containers[0].Args
+		Expect(argValue(args, "--dtype")).To(Equal("float16"))
+		Expect(argValue(args, "--quantization")).To(Equal("awq"))
+	})
+
+	It("probes the health endpoint", func() {
+		d := engineDeployment(a1.AIEngineNameSglang, model("facebook/opt-125m"), nil)
+		c := d.Spec.Template.Spec.Containers[0]
+
+		for _, p := range []*corev1.Probe{c.StartupProbe, c.ReadinessProbe, c.LivenessProbe} {
+			Expect(p.HTTPGet.Path).To(Equal("/health"))
+			Expect(p.HTTPGet.Port.IntValue()).To(Equal(30000))
+		}
+	})
+})
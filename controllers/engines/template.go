@@ -0,0 +1,206 @@
+package engines
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"text/template"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aideployment"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+)
+
+const templateDefaultPort = int32(8000)
+
+var ErrUnknownEngine = errors.New("unknown engine")
+
+// Get finds the engine named in the AIDeployment spec. Registered engines
+// take precedence over AIEngineTemplates with the same name.
+func Get(ctx context.Context, c ctrlClient.Reader, ai *a1.AIDeployment) (Definition, error) {
+	if def, ok := Lookup(ai.Spec.Engine.Name); ok {
+		return def, nil
+	}
+
+	tmpl := &a1.AIEngineTemplate{}
+	if err := c.Get(ctx, ctrlClient.ObjectKey{Name: string(ai.Spec.Engine.Name)}, tmpl); err != nil {
+		if apierrors.IsNotFound(err) {
+			return Definition{}, fmt.Errorf("%w: %s", ErrUnknownEngine, ai.Spec.Engine.Name)
+		}
+
+		return Definition{}, err
+	}
+
+	return TemplateDefinition(tmpl), nil
+}
+
+// TemplateDefinition creates the engine definition for an AIEngineTemplate
+func TemplateDefinition(tmpl *a1.AIEngineTemplate) Definition {
+	modelMapKey := tmpl.Spec.ModelMapKey
+	if modelMapKey == "" {
+		modelMapKey = tmpl.Name
+	}
+
+	port := tmpl.Spec.Port
+	if port == 0 {
+		port = templateDefaultPort
+	}
+
+	return Definition{
+		Name:        a1.AIEngineName(tmpl.Name),
+		ModelMapKey: modelMapKey,
+		DefaultPort: port,
+		New: func(ai *a1.AIDeployment, models []aimodelmap.ResolvedModel) (aideployment.MLEngine, error) {
+			return &TemplateEngine{Template: tmpl, AIDeployment: ai, Models: models}, nil
+		},
+		Validate: func(_ *a1.AIDeployment, models []aimodelmap.ResolvedModel) error {
+			if tmpl.Spec.MaxModels > 0 && len(models) > tmpl.Spec.MaxModels {
+				return fmt.Errorf("engine %s can serve at most %d models", tmpl.Name, tmpl.Spec.MaxModels)
+			}
+
+			return nil
+		},
+	}
+}
+
+// TemplateEngine runs the container described by an AIEngineTemplate
+type TemplateEngine struct {
+	Template     *a1.AIEngineTemplate
+	AIDeployment *a1.AIDeployment
+	Models       []aimodelmap.ResolvedModel
+}
+
+// templateModel is what the argument templates see of a model
+type templateModel struct {
+	Name         string
+	Variant      string
+	Uri          string
+	DataType     string
+	Quantization string
+}
+
+type templateData struct {
+	Model   templateModel
+	Models  []templateModel
+	Options map[string]string
+	Port    int32
+}
+
+func (t *TemplateEngine) Port() int32 {
+	if t.Template.Spec.Port != 0 {
+		return t.Template.Spec.Port
+	}
+
+	return templateDefaultPort
+}
+
+func (t *TemplateEngine) args() ([]string, error) {
+	data := templateData{
+		Models:  make([]templateModel, 0, len(t.Models)),
+		Options: t.AIDeployment.Spec.Engine.Options,
+		Port:    t.Port(),
+	}
+
+	for _, m := range t.Models {
+		data.Models = append(data.Models, templateModel{
+			Name:         m.Name,
+			Variant:      m.Variant,
+			Uri:          m.Spec.Uri,
+			DataType:     string(m.Spec.DataType),
+			Quantization: string(m.Spec.Quantization),
+		})
+	}
+	if len(data.Models) > 0 {
+		data.Model = data.Models[0]
+	}
+
+	args := make([]string, 0, len(t.Template.Spec.Args))
+	for i, a := range t.Template.Spec.Args {
+		tmpl, err := template.New(fmt.Sprintf("arg%d", i)).Option("missingkey=zero").Parse(a)
+		if err != nil {
+			return nil, fmt.Errorf("engine %s: parsing args[%d]: %w", t.Template.Name, i, err)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("engine %s: rendering args[%d]: %w", t.Template.Name, i, err)
+		}
+
+		if buf.Len() > 0 {
+			args = append(args, buf.String())
+		}
+	}
+
+	return args, nil
+}
+
+func (t *TemplateEngine) probe(path string) *v1.Probe {
+	if path == "" {
+		return nil
+	}
+
+	return &v1.Probe{
+		ProbeHandler: v1.ProbeHandler{
+			HTTPGet: &v1.HTTPGetAction{
+				Path: path,
+				Port: intstr.FromInt(int(t.Port())),
+			},
+		},
+	}
+}
+
+func (t *TemplateEngine) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
+	ai := t.AIDeployment
+
+	args, err := t.args()
+	if err != nil {
+		return nil, err
+	}
+
+	deployment := newDeployment(ai, owner)
+	pod := &deployment.Spec.Template.Spec
+
+	container := v1.Container{
+		Name:    constants.ContainerEngineName,
+		Image:   t.Template.Spec.Image,
+		Command: t.Template.Spec.Command,
+		Args:    args,
+		Env:     append(append([]v1.EnvVar{}, t.Template.Spec.Env...), ai.Spec.Env...),
+		Ports: []v1.ContainerPort{
+			{ContainerPort: t.Port(), Name: "http", Protocol: v1.ProtocolTCP},
+		},
+		StartupProbe:   t.probe(t.Template.Spec.Probes.Startup),
+		ReadinessProbe: t.probe(t.Template.Spec.Probes.Readiness),
+		LivenessProbe:  t.probe(t.Template.Spec.Probes.Liveness),
+	}
+
+	if container.StartupProbe != nil {
+		container.StartupProbe.PeriodSeconds = 10
+		container.StartupProbe.FailureThreshold = 120
+		mergeProbe(ai.Spec.Deployment.StartupProbe, container.StartupProbe)
+	}
+	if container.ReadinessProbe != nil {
+		mergeProbe(ai.Spec.Deployment.ReadinessProbe, container.ReadinessProbe)
+	}
+	if container.LivenessProbe != nil {
+		container.LivenessProbe.PeriodSeconds = 30
+		container.LivenessProbe.TimeoutSeconds = 15
+		container.LivenessProbe.FailureThreshold = 10
+		mergeProbe(ai.Spec.Deployment.LivenessProbe, container.LivenessProbe)
+	}
+
+	if err := addEngineContainers(ai, pod, container); err != nil {
+		return nil, err
+	}
+
+	return deployment, nil
+}
@@ -0,0 +1,94 @@
+package engines_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/engines"
+)
+
+var _ = Describe("TemplateEngine", func() {
+	newTemplate := func(args ...string) *a1.AIEngineTemplate {
+		return &a1.AIEngineTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "custom"},
+			Spec: a1.AIEngineTemplateSpec{
+				Image: "example.com/engine:latest",
+				Port:  9000,
+				Args:  args,
+				Probes: a1.AIEngineTemplateProbes{
+					Readiness: "/health",
+				},
+			},
+		}
+	}
+
+	models := func() []aimodelmap.ResolvedModel {
+		opt := model("facebook/opt-125m")
+		opt.Spec.DataType = "float16"
+		phi := model("microsoft/phi-2")
+		phi.Name = "phi"
+
+		return []aimodelmap.ResolvedModel{opt, phi}
+	}
+
+	DescribeTable("renders the arguments",
+		func(args []string, options map[string]string, expected []string, err string) {
+			ai := newDeployment("custom")
+			ai.Spec.Engine.Options = options
+
+			engine := &engines.TemplateEngine{Template: newTemplate(args...), AIDeployment: ai, Models: models()}
+			d, e := engine.Deployment(ai)
+			if err != "" {
+				Expect(e).To(MatchError(ContainSubstring(err)))
+				return
+			}
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(d.Spec.Template.Spec.Containers[0].Args).To(Equal(expected))
+		},
+		Entry("the first model and the port",
+			[]string{"--model={{ .Model.Uri }}", "--port={{ .Port }}"}, nil,
+			[]string{"--model=facebook/opt-125m", "--port=9000"}, ""),
+		Entry("every model",
+			[]string{"{{ range .Models }}{{ .Name }}={{ .Uri }} {{ end }}"}, nil,
+			[]string{"llm=facebook/opt-125m phi=microsoft/phi-2 "}, ""),
+		Entry("optional flags are dropped when empty",
+			[]string{"{{ with .Model.DataType }}--dtype={{ . }}{{ end }}", "{{ with .Model.Quantization }}--quantization={{ . }}{{ end }}"}, nil,
+			[]string{"--dtype=float16"}, ""),
+		Entry("engine options",
+			[]string{"--threads={{ .Options.threads }}", "{{ .Options.missing }}"}, map[string]string{"threads": "4"},
+			[]string{"--threads=4"}, ""),
+		Entry("a template which doesn't parse",
+			[]string{"{{ .Model.Uri"}, nil, nil, "parsing args[0]"),
+		Entry("a template which fails to render",
+			[]string{"{{ .Model.Missing }}"}, nil, nil, "rendering args[0]"),
+	)
+
+	It("runs the template's container", func() {
+		ai := newDeployment("custom")
+		engine := &engines.TemplateEngine{Template: newTemplate(), AIDeployment: ai, Models: models()}
+
+		d, err := engine.Deployment(ai)
+		Expect(err).NotTo(HaveOccurred())
+
+		c := d.Spec.Template.Spec.Containers[0]
+		Expect(c.Name).To(Equal(constants.ContainerEngineName))
+		Expect(c.Image).To(Equal("example.com/engine:latest"))
+		Expect(c.Ports).To(ConsistOf(HaveField("ContainerPort", BeEquivalentTo(9000))))
+		Expect(c.ReadinessProbe.HTTPGet.Path).To(Equal("/health"))
+		Expect(c.StartupProbe).To(BeNil())
+	})
+
+	It("limits the number of models", func() {
+		tmpl := newTemplate()
+		tmpl.Spec.MaxModels = 1
+
+		Expect(engines.TemplateDefinition(tmpl).Create(newDeployment("custom"), models())).Error().To(
+			MatchError(ContainSubstring("at most 1 models")),
+		)
+	})
+})
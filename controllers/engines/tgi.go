@@ -0,0 +1,184 @@
+package engines
+
+import (
+	"fmt"
+	"strconv"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aideployment"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	tgiPort            = int32(8080)
+	tgiDataVolumePath  = "/data"
+	tgiDataVolumeName  = "data"
+	tgiDefaultImageTag = constants.ImageTagLatest
+)
+
+// tgiDataTypes are the values TGI accepts for --dtype
+var tgiDataTypes = map[string]bool{
+	string(a1.AIModelDataTypeFloat16):  true,
+	string(a1.AIModelDataTypeBFloat16): true,
+}
+
+type Tgi struct {
+	AIDeployment *a1.AIDeployment
+	model        aimodelmap.ResolvedModel
+}
+
+func init() {
+	Register(Definition{
+		Name:          a1.AIEngineNameTgi,
+		ModelMapKey:   a1.AIModelMapKeyTgi,
+		DefaultPort:   tgiPort,
+		New:           NewTgi,
+		Validate:      singleModel,
+		ModelCache:    true,
+		ModelPrefetch: true,
+	})
+}
+
+func NewTgi(ai *a1.AIDeployment, models []aimodelmap.ResolvedModel) (aideployment.MLEngine, error) {
+	if err := singleModel(ai, models); err != nil {
+		return nil, err
+	}
+
+	return &Tgi{AIDeployment: ai, model: models[0]}, nil
+}
+
+func (t *Tgi) Port() int32 {
+	return tgiPort
+}
+
+func (t *Tgi) args() ([]string, error) {
+	args := []string{
+		"--model-id", modelPath(t.AIDeployment, t.model),
+		"--port", strconv.Itoa(int(t.Port())),
+	}
+
+	engineOpts := make(map[string]string)
+	if t.model.Spec.DataType != "" {
+		engineOpts[constants.DtypeKey] = string(t.model.Spec.DataType)
+	}
+	if t.model.Spec.Quantization != "" {
+		engineOpts[constants.QuantizationKey] = string(t.model.Spec.Quantization)
+	}
+	opts := utils.MergeMaps(engineOpts, t.AIDeployment.Spec.Engine.Options)
+
+	if dtype, ok := opts[constants.DtypeKey]; ok {
+		if !tgiDataTypes[dtype] {
+			return nil, fmt.Errorf("TGI dtype must be %s or %s", a1.AIModelDataTypeFloat16, a1.AIModelDataTypeBFloat16)
+		}
+		args = append(args, "--dtype", dtype)
+	}
+
+	if quant, ok := opts[constants.QuantizationKey]; ok {
+		if !utils.IsAlphanumeric(quant) {
+			return nil, fmt.Errorf("quantization must be alphanumeric")
+		}
+		args = append(args, "--quantize", quant)
+	}
+
+	tp, err := tensorParallelSize(t.AIDeployment)
+	if err != nil {
+		return nil, err
+	}
+	if tp > 1 {
+		args = append(args, "--num-shard", strconv.FormatInt(tp, 10))
+	}
+
+	return args, nil
+}
+
+func (t *Tgi) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
+	args, err := t.args()
+	if err != nil {
+		return nil, err
+	}
+
+	tp, err := tensorParallelSize(t.AIDeployment)
+	if err != nil {
+		return nil, err
+	}
+
+	imageTag := tgiDefaultImageTag
+	if t.AIDeployment.Spec.Engine.Options[constants.ImageTagKey] != "" {
+		imageTag = t.AIDeployment.Spec.Engine.Options[constants.ImageTagKey]
+	}
+	imageRepo := constants.ImageRepositoryTgi
+	if t.AIDeployment.Spec.Engine.Options[constants.ImageRepositoryKey] != "" {
+		imageRepo = t.AIDeployment.Spec.Engine.Options[constants.ImageRepositoryKey]
+	}
+
+	healthProbeHandler := v1.ProbeHandler{
+		HTTPGet: &v1.HTTPGetAction{
+			Path: "/health",
+			Port: intstr.FromInt(int(t.Port())),
+		},
+	}
+
+	container := v1.Container{
+		ImagePullPolicy: v1.PullAlways,
+		Name:            constants.ContainerEngineName,
+		Image:           fmt.Sprintf("%s:%s", imageRepo, imageTag),
+		Env:             hfTokenEnv(t.model.Spec, t.AIDeployment.Spec.Env),
+		Args:            args,
+		Ports: []v1.ContainerPort{
+			{ContainerPort: t.Port(), Name: "http", Protocol: v1.ProtocolTCP},
+		},
+		VolumeMounts: []v1.VolumeMount{
+			{
+				Name:      tgiDataVolumeName,
+				MountPath: tgiDataVolumePath,
+			},
+		},
+		StartupProbe: &v1.Probe{
+			InitialDelaySeconds: 3,
+			PeriodSeconds:       5,
+			FailureThreshold:    360,
+			ProbeHandler:        healthProbeHandler,
+		},
+		ReadinessProbe: &v1.Probe{
+			FailureThreshold: 3,
+			ProbeHandler:     healthProbeHandler,
+		},
+		LivenessProbe: &v1.Probe{
+			PeriodSeconds:    30,
+			TimeoutSeconds:   15,
+			FailureThreshold: 10,
+			ProbeHandler:     healthProbeHandler,
+		},
+	}
+
+	mergeProbe(t.AIDeployment.Spec.Deployment.StartupProbe, container.StartupProbe)
+	mergeProbe(t.AIDeployment.Spec.Deployment.ReadinessProbe, container.ReadinessProbe)
+	mergeProbe(t.AIDeployment.Spec.Deployment.LivenessProbe, container.LivenessProbe)
+
+	deployment := newDeployment(t.AIDeployment, owner)
+	pod := &deployment.Spec.Template.Spec
+	pod.Volumes = append(pod.Volumes, v1.Volume{
+		Name: tgiDataVolumeName,
+		VolumeSource: v1.VolumeSource{
+			EmptyDir: &v1.EmptyDirVolumeSource{},
+		},
+	})
+
+	if err := addSharedMemory(t.AIDeployment, tp, pod, &container); err != nil {
+		return nil, err
+	}
+
+	initContainers := addModelDownload(t.AIDeployment, t.model, pod, &container)
+
+	if err := addEngineContainers(t.AIDeployment, pod, container, initContainers...); err != nil {
+		return nil, err
+	}
+
+	return deployment, nil
+}
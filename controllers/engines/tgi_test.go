@@ -0,0 +1,129 @@
+package engines_test
+
+import (
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/engines"
+	"github.com/premAI-io/prem-operator/controllers/modelcache"
+)
+
+// withGPUs requests n Nvidia GPUs for the engine
+func withGPUs(n int) func(*a1.AIDeployment) {
+	return func(ai *a1.AIDeployment) {
+		ai.Spec.Deployment.Accelerator = &a1.Accelerator{Interface: a1.AcceleratorInterfaceCUDA}
+		ai.Spec.Deployment.Resources.Requests = corev1.ResourceList{
+			constants.NvidiaGPULabel: resource.MustParse(strconv.Itoa(n)),
+		}
+	}
+}
+
+// withModelCache makes the engine load its model from a ModelCache
+func withModelCache(ai *a1.AIDeployment) {
+	ai.Spec.ModelCacheRef = &corev1.LocalObjectReference{Name: "models"}
+}
+
+// engineDeployment creates the Deployment of the engine with name for the
+// single model m
+func engineDeployment(name a1.AIEngineName, m aimodelmap.ResolvedModel, mutate func(*a1.AIDeployment)) *appsv1.Deployment {
+	ai := newDeployment(name)
+	if mutate != nil {
+		mutate(ai)
+	}
+
+	def, ok := engines.Lookup(name)
+	Expect(ok).To(BeTrue())
+	mle, err := def.Create(ai, []aimodelmap.ResolvedModel{m})
+	Expect(err).NotTo(HaveOccurred())
+
+	d, err := mle.Deployment(ai)
+	Expect(err).NotTo(HaveOccurred())
+
+	return d
+}
+
+func argValue(args []string, flag string) string {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == flag {
+			return args[i+1]
+		}
+	}
+
+	return ""
+}
+
+func hasVolume(d *appsv1.Deployment, name string) bool {
+	for _, v := range d.Spec.Template.Spec.Volumes {
+		if v.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+var _ = Describe("Tgi", func() {
+	DescribeTable("shards the model across GPUs",
+		func(mutate func(*a1.AIDeployment), shards string) {
+			d := engineDeployment(a1.AIEngineNameTgi, model("facebook/opt-125m"), mutate)
+			c := d.Spec.Template.Spec.Containers[0]
+
+			Expect(argValue(c.Args, "--num-shard")).To(Equal(shards))
+			Expect(hasVolume(d, "dshm")).To(Equal(shards != ""))
+			if shards != "" {
+				Expect(c.VolumeMounts).To(ContainElement(HaveField("MountPath", "/dev/shm")))
+			}
+		},
+		Entry("without GPUs", nil, ""),
+		Entry("with one GPU", withGPUs(1), ""),
+		Entry("with four GPUs", withGPUs(4), "4"),
+		Entry("with the tensor parallel size option", func(ai *a1.AIDeployment) {
+			withGPUs(4)(ai)
+			ai.Spec.Engine.Options = map[string]string{constants.TensorParallelSizeKey: "2"}
+		}, "2"),
+	)
+
+	DescribeTable("loads the model from",
+		func(uri string, mutate func(*a1.AIDeployment), path func(aimodelmap.ResolvedModel) string, initContainers int) {
+			m := model(uri)
+			d := engineDeployment(a1.AIEngineNameTgi, m, mutate)
+
+			Expect(argValue(d.Spec.Template.Spec.Containers[0].Args, "--model-id")).To(Equal(path(m)))
+			Expect(d.Spec.Template.Spec.InitContainers).To(HaveLen(initContainers))
+		},
+		Entry("the Hugging Face Hub", "facebook/opt-125m", nil,
+			func(m aimodelmap.ResolvedModel) string { return m.Spec.Uri }, 0),
+		Entry("the downloads volume", "s3://models/opt-125m/", nil,
+			func(m aimodelmap.ResolvedModel) string { return "/downloads/" + m.HostName }, 1),
+		Entry("a ModelCache", "s3://models/opt-125m/", withModelCache, modelcache.Dir, 0),
+	)
+
+	It("passes the dtype and quantization", func() {
+		m := model("TheBloke/TinyLlama-1.1B-Chat-v1.0-AWQ")
+		m.Spec.DataType = a1.AIModelDataTypeFloat16
+		m.Spec.Quantization = a1.AIModelQuantizationAWQ
+
+		d := engineDeployment(a1.AIEngineNameTgi, m, nil)
+		args := d.Spec.Template.Spec.Containers[0].Args
+		Expect(argValue(args, "--dtype")).To(Equal("float16"))
+		Expect(argValue(args, "--quantize")).To(Equal("awq"))
+	})
+
+	It("probes the health endpoint", func() {
+		d := engineDeployment(a1.AIEngineNameTgi, model("facebook/opt-125m"), nil)
+		c := d.Spec.Template.Spec.Containers[0]
+
+		for _, p := range []*corev1.Probe{c.StartupProbe, c.ReadinessProbe, c.LivenessProbe} {
+			Expect(p.HTTPGet.Path).To(Equal("/health"))
+			Expect(p.HTTPGet.Port.IntValue()).To(Equal(8080))
+		}
+	})
+})
@@ -2,46 +2,214 @@ package engines
 
 import (
 	"fmt"
+	"net/url"
+	"path"
 	"strings"
 
 	"github.com/premAI-io/prem-operator/controllers/aideployment"
 	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
 	"github.com/premAI-io/prem-operator/controllers/constants"
-	"github.com/premAI-io/prem-operator/pkg/utils"
+	"github.com/premAI-io/prem-operator/controllers/modelcache"
+	"github.com/premAI-io/prem-operator/pkg/downloader"
 
 	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
-	"github.com/premAI-io/prem-operator/controllers/resources"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+const (
+	tritonPort          = int32(8000)
+	tritonGRPCPort      = int32(8001)
+	tritonMetricsPort   = int32(8002)
+	tritonModelsPath    = "/models"
+	tritonModelVersion  = "1"
+	tritonConfigSuffix  = ".pbtxt"
+	tritonConfigFile    = "config.pbtxt"
+	tritonModelsVolume  = "models"
+	tritonArchiveMarker = ".tar"
+)
+
+// tritonModelScript finishes laying out a model in the Triton model
+// repository after the downloader has fetched its file. A model in the
+// ModelCache is linked into the repository instead, the files of its version
+// directory, or each model of a cached archive. The config, if any, becomes
+// the model's config.pbtxt, replacing a link to the cache. A model with only
+// a config, such as an ensemble, gets an empty version directory.
+const tritonModelScript = `set -e
+link() { mkdir -p "$2"; for f in "$1"/*; do ln -sfn "$f" "$2/"; done; }
+if [ -n "$CACHED_REPOSITORY" ]; then for d in "$CACHED_REPOSITORY"/*/; do link "${d%/}" "` + tritonModelsPath + `/$(basename "$d")"; done; fi
+if [ -n "$CACHED_VERSION" ]; then link "$CACHED_VERSION" "$MODEL_DIR/` + tritonModelVersion + `"; fi
+if [ -n "$MODEL_VERSION" ]; then mkdir -p "$MODEL_DIR/$MODEL_VERSION"; fi
+if [ -n "$CONFIG_FILE" ]; then mkdir -p "$MODEL_DIR" && rm -f "$MODEL_DIR/` + tritonConfigFile + `" && cp -v "$CONFIG_FILE" "$MODEL_DIR/` + tritonConfigFile + `"; fi`
+
 type Triton struct {
 	AIDeployment *a1.AIDeployment
 	Models       []aimodelmap.ResolvedModel
 }
 
+func init() {
+	Register(Definition{
+		Name:        a1.AIEngineNameTriton,
+		ModelMapKey: a1.AIModelMapKeyTensorRT,
+		DefaultPort: tritonPort,
+		New: func(ai *a1.AIDeployment, m []aimodelmap.ResolvedModel) (aideployment.MLEngine, error) {
+			return NewTriton(ai, m), nil
+		},
+		Validate:   validateTritonModels,
+		ModelCache: true,
+		ModelCacheFile: func(m aimodelmap.ResolvedModel) bool {
+			return !tritonArchive(m) && !downloader.IsDirectory(m.Spec.Uri)
+		},
+	})
+}
+
+// tritonArchive is true if m's URI is a tar archive of a model repository
+func tritonArchive(m aimodelmap.ResolvedModel) bool {
+	return strings.Contains(m.Spec.Uri, tritonArchiveMarker)
+}
+
+func validateTritonModels(_ *a1.AIDeployment, models []aimodelmap.ResolvedModel) error {
+	if len(models) == 0 {
+		return ErrModelsNotSpecified
+	}
+
+	// Each model's directory in the repository is named after its HostName
+	hostNames := map[string]bool{}
+	for _, m := range models {
+		if hostNames[m.HostName] {
+			// Inline models all have the HostName <deployment>-model
+			return fmt.Errorf("more than one model is named %s in the model repository, only one model can be inline, use an AIModelMap for the others", m.HostName)
+		}
+		hostNames[m.HostName] = true
+
+		if m.Spec.Uri == "" && m.Spec.EngineConfigFile == "" {
+			return fmt.Errorf("model %s needs a URI or an engine config file", m.HostName)
+		}
+
+		if m.Spec.Uri != "" && !downloader.Supported(m.Spec.Uri) {
+			return fmt.Errorf("model %s has an invalid URI, it must be a URL the model can be downloaded from", m.HostName)
+		}
+	}
+
+	return nil
+}
+
+// tritonModelInitContainers creates the init containers which put a model
+// in the model repository. The model's directory is named after its HostName,
+// which is what ensembles and clients must refer to it as. A file at the URI
+// is downloaded into the version directory, as are the files of a URI which
+// is a directory, while an archive contains a whole model repository and is
+// unpacked into its root. A model in the ModelCache is linked from it rather
+// than downloaded.
+func tritonModelInitContainers(ai *a1.AIDeployment, m aimodelmap.ResolvedModel, image string, hasConfigs bool) ([]v1.Container, error) {
+	modelsMount := v1.VolumeMount{
+		Name:      tritonModelsVolume,
+		MountPath: tritonModelsPath,
+	}
+	modelDir := fmt.Sprintf("%s/%s", tritonModelsPath, m.HostName)
+	containers := []v1.Container{}
+	inCache := m.Spec.Uri != "" && cached(ai, m)
+
+	if m.Spec.Uri != "" && !inCache {
+		name := fmt.Sprintf("init-download-%s", m.HostName)
+		versionDir := fmt.Sprintf("%s/%s", modelDir, tritonModelVersion)
+
+		switch {
+		case tritonArchive(m):
+			containers = append(containers, downloadContainer(ai.Spec.Env, name, m.Spec, tritonModelsPath, true, modelsMount))
+		case downloader.IsDirectory(m.Spec.Uri):
+			containers = append(containers, downloadContainer(ai.Spec.Env, name, m.Spec, versionDir, false, modelsMount))
+		default:
+			u, err := url.Parse(m.Spec.Uri)
+			if err != nil {
+				return nil, fmt.Errorf("model %s: invalid URI: %w", m.HostName, err)
+			}
+			output := fmt.Sprintf("%s/%s", versionDir, path.Base(u.Path))
+			containers = append(containers, downloadContainer(ai.Spec.Env, name, m.Spec, output, false, modelsMount))
+		}
+	}
+
+	configFile := ""
+	if m.Spec.EngineConfigFile != "" {
+		configFile = fmt.Sprintf("%s/%s/%s%s", engineConfigMountPath, engineConfigDir, m.HostName, tritonConfigSuffix)
+	}
+	if configFile == "" && m.Spec.Uri != "" && !inCache {
+		return containers, nil
+	}
+
+	// Only a model without a file needs its version directory created
+	version := ""
+	if m.Spec.Uri == "" {
+		version = tritonModelVersion
+	}
+
+	container := v1.Container{
+		ImagePullPolicy: v1.PullAlways,
+		Name:            fmt.Sprintf("init-%s", m.HostName),
+		Image:           image,
+		Command:         []string{"sh", "-c"},
+		// needs to be in a single argument as sh -c accepts a single input
+		Args: []string{tritonModelScript},
+		Env: []v1.EnvVar{
+			{Name: "MODEL_DIR", Value: modelDir},
+			{Name: "MODEL_VERSION", Value: version},
+			{Name: "CONFIG_FILE", Value: configFile},
+		},
+		VolumeMounts: []v1.VolumeMount{modelsMount},
+	}
+
+	if inCache {
+		cachedDir := "CACHED_VERSION"
+		if tritonArchive(m) {
+			cachedDir = "CACHED_REPOSITORY"
+		}
+		container.Env = append(container.Env, v1.EnvVar{Name: cachedDir, Value: modelcache.Dir(m)})
+		container.VolumeMounts = append(container.VolumeMounts, modelCacheMount())
+	}
+
+	if hasConfigs {
+		container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+			Name:      engineConfigVolumeName,
+			MountPath: engineConfigMountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	return append(containers, container), nil
+}
+
 func NewTriton(ai *a1.AIDeployment, m []aimodelmap.ResolvedModel) aideployment.MLEngine {
 	return &Triton{AIDeployment: ai, Models: m}
 
 }
 
 func (l *Triton) MetricsPort() int32 {
-	return 8002
+	return tritonMetricsPort
+}
+
+func (l *Triton) GRPCPort() int32 {
+	return tritonGRPCPort
 }
 
 func (l *Triton) Port() int32 {
-	return 8000
+	return tritonPort
 }
 
-func (l *Triton) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
-	objMeta := metav1.ObjectMeta{
-		Name:            l.AIDeployment.Name,
-		Namespace:       l.AIDeployment.Namespace,
-		OwnerReferences: resources.GenOwner(owner),
+// ServicePorts exposes the HTTP and gRPC inference APIs and the Prometheus
+// metrics
+func (l *Triton) ServicePorts() []v1.ServicePort {
+	http, grpc := "http", "grpc"
+
+	return []v1.ServicePort{
+		{Name: "http", Port: l.Port(), TargetPort: intstr.FromString("http"), AppProtocol: &http},
+		{Name: "grpc", Port: l.GRPCPort(), TargetPort: intstr.FromString("grpc"), AppProtocol: &grpc},
+		{Name: "metrics", Port: l.MetricsPort(), TargetPort: intstr.FromString("metrics"), AppProtocol: &http},
 	}
+}
 
+func (l *Triton) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
 	imageTag := constants.ImageTagTritonDefault
 	if l.AIDeployment.Spec.Engine.Options[constants.ImageTagKey] != "" {
 		imageTag = l.AIDeployment.Spec.Engine.Options[constants.ImageTagKey]
@@ -52,17 +220,9 @@ func (l *Triton) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
 		imageRepository = l.AIDeployment.Spec.Engine.Options[constants.ImageRepositoryKey]
 	}
 
-	deployment := appsv1.Deployment{}
-	if l.AIDeployment.Spec.Deployment.PodTemplate != nil {
-		deployment.Spec.Template = *l.AIDeployment.Spec.Deployment.PodTemplate.DeepCopy()
-	} else {
-		deployment.Spec.Template = v1.PodTemplateSpec{}
-	}
-	deployment.Spec.Replicas = l.AIDeployment.Spec.Deployment.Replicas
+	deployment := newDeployment(l.AIDeployment, owner)
 	pod := &deployment.Spec.Template.Spec
 
-	serviceAccount := false
-
 	image := fmt.Sprintf("%s:%s", imageRepository, imageTag)
 
 	expose := &v1.Container{
@@ -71,12 +231,17 @@ func (l *Triton) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
 		Image:           image,
 		Args: []string{
 			"tritonserver",
-			"--model-repository=/models",
+			"--model-repository=" + tritonModelsPath,
+		},
+		Ports: []v1.ContainerPort{
+			{ContainerPort: l.Port(), Name: "http", Protocol: v1.ProtocolTCP},
+			{ContainerPort: l.GRPCPort(), Name: "grpc", Protocol: v1.ProtocolTCP},
+			{ContainerPort: l.MetricsPort(), Name: "metrics", Protocol: v1.ProtocolTCP},
 		},
 		VolumeMounts: []v1.VolumeMount{
 			{
-				Name:      "models",
-				MountPath: "/models",
+				Name:      tritonModelsVolume,
+				MountPath: tritonModelsPath,
 			},
 		},
 		StartupProbe: &v1.Probe{
@@ -118,74 +283,41 @@ func (l *Triton) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
 	mergeProbe(l.AIDeployment.Spec.Deployment.ReadinessProbe, expose.ReadinessProbe)
 	mergeProbe(l.AIDeployment.Spec.Deployment.LivenessProbe, expose.LivenessProbe)
 
-	pod.AutomountServiceAccountToken = &serviceAccount
 	pod.Volumes = append(pod.Volumes, v1.Volume{
-		Name: "models",
+		Name: tritonModelsVolume,
 		VolumeSource: v1.VolumeSource{
 			EmptyDir: &v1.EmptyDirVolumeSource{},
 		},
 	})
 
+	configVolume, err := engineConfigVolume(a1.AIEngineNameTriton, l.Models, tritonConfigSuffix)
+	if err != nil {
+		return nil, err
+	}
+	if configVolume != nil {
+		pod.Volumes = append(pod.Volumes, *configVolume)
+	}
+
+	initContainers := make([]v1.Container, 0, len(l.Models))
+	inCache := false
 	for _, m := range l.Models {
-		// if the URL doesn't point to a tar file
-		if strings.HasPrefix(m.Spec.Uri, "http") && !strings.Contains(m.Spec.Uri, ".tar") {
-			pod.InitContainers = append(pod.InitContainers, v1.Container{
-				ImagePullPolicy: v1.PullAlways,
-				Name:            fmt.Sprintf("init-%s", m.Name),
-				Image:           image,
-				Command:         []string{"sh", "-c"},
-				// needs to be in a single line as sh -c accepts a single input
-				Args: []string{"curl --create-dirs -O --output-dir /models/$MODEL_NAME/1 $MODEL_PATH"},
-				Env: []v1.EnvVar{
-					{Name: "MODEL_NAME", Value: m.Name},
-					{Name: "MODEL_PATH", Value: m.Spec.Uri},
-				},
-				VolumeMounts: []v1.VolumeMount{
-					{
-						Name:      "models",
-						MountPath: "/models",
-					},
-				},
-			})
-		} else if strings.HasPrefix(m.Spec.Uri, "http") {
-			pod.InitContainers = append(pod.InitContainers, v1.Container{
-				ImagePullPolicy: v1.PullAlways,
-				Name:            fmt.Sprintf("init-%s", m.Name),
-				Image:           image,
-				Command:         []string{"sh", "-c"},
-				// needs to be in a single line as sh -c accepts a single input
-				Args: []string{"curl -s -L $MODEL_PATH | tar xvz - -C /models"},
-				Env: []v1.EnvVar{
-					{Name: "MODEL_NAME", Value: m.Name},
-					{Name: "MODEL_PATH", Value: m.Spec.Uri},
-				},
-				VolumeMounts: []v1.VolumeMount{
-					{
-						Name:      "models",
-						MountPath: "/models",
-					},
-				},
-			})
-		} else {
-			return nil, fmt.Errorf("invalid model URI, requires valid model url with \"http\" for downloading models")
+		containers, err := tritonModelInitContainers(l.AIDeployment, m, image, configVolume != nil)
+		if err != nil {
+			return nil, err
 		}
+		initContainers = append(initContainers, containers...)
+		inCache = inCache || (m.Spec.Uri != "" && cached(l.AIDeployment, m))
 	}
 
-	pod.Containers = append(pod.Containers, *expose)
-	deploymentLabels := resources.GenDefaultLabels(l.AIDeployment.Name)
-	deployment.Spec.Template.Labels = utils.MergeMaps(
-		deploymentLabels,
-		deployment.Spec.Template.Labels,
-		l.AIDeployment.Spec.Deployment.Labels,
-	)
-
-	deployment.Spec.Template.Annotations = utils.MergeMaps(
-		deployment.Spec.Template.Annotations,
-		l.AIDeployment.Spec.Deployment.Annotations,
-	)
+	// The links in the repository point into the cache
+	if inCache {
+		addModelCacheVolume(l.AIDeployment, pod)
+		expose.VolumeMounts = append(expose.VolumeMounts, modelCacheMount())
+	}
 
-	deployment.ObjectMeta = objMeta
-	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: deploymentLabels}
+	if err := addEngineContainers(l.AIDeployment, pod, *expose, initContainers...); err != nil {
+		return nil, err
+	}
 
-	return &deployment, nil
+	return deployment, nil
 }
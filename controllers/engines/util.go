@@ -1,10 +1,98 @@
 package engines
 
 import (
+	"fmt"
+	"strconv"
+
 	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aideployment"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
+const (
+	engineConfigVolumeName = "configs"
+	engineConfigMountPath  = "/" + engineConfigVolumeName
+	engineConfigDir        = "engine"
+
+	shmVolumeName = "dshm"
+	shmMountPath  = "/dev/shm"
+)
+
+// shmSizePerGPU is the default size of /dev/shm, NCCL needs more than the
+// container runtime's default of 64Mi when a model is split across GPUs
+var shmSizePerGPU = resource.MustParse("2Gi")
+
+// singleModel validates engines which serve exactly one model
+func singleModel(_ *a1.AIDeployment, models []aimodelmap.ResolvedModel) error {
+	if len(models) == 0 {
+		return ErrModelsNotSpecified
+	}
+
+	if len(models) > 1 {
+		return ErrorOnlyOneModel
+	}
+
+	return nil
+}
+
+// tensorParallelSize is the number of GPUs to split the model across. It is
+// the tensorParallelSize engine option if set, otherwise the number of GPUs
+// the deployment requests.
+func tensorParallelSize(ai *a1.AIDeployment) (int64, error) {
+	if tp, ok := ai.Spec.Engine.Options[constants.TensorParallelSizeKey]; ok {
+		n, err := strconv.ParseInt(tp, 10, 64)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("%s must be a positive integer", constants.TensorParallelSizeKey)
+		}
+
+		return n, nil
+	}
+
+	gpus, err := aideployment.NeededGPUs(ai.Spec.Deployment)
+	if err != nil {
+		return 0, err
+	}
+
+	return gpus.Value(), nil
+}
+
+// addSharedMemory mounts a memory backed volume at /dev/shm in the container
+// when the model is split across GPUs. Its size is the shmSize engine option
+// if set, otherwise shmSizePerGPU for each GPU.
+func addSharedMemory(ai *a1.AIDeployment, tp int64, pod *v1.PodSpec, container *v1.Container) error {
+	if tp < 2 {
+		return nil
+	}
+
+	size := shmSizePerGPU.DeepCopy()
+	size.Mul(tp)
+	if s, ok := ai.Spec.Engine.Options[constants.ShmSizeKey]; ok {
+		var err error
+		if size, err = resource.ParseQuantity(s); err != nil {
+			return fmt.Errorf("%s: %w", constants.ShmSizeKey, err)
+		}
+	}
+
+	pod.Volumes = append(pod.Volumes, v1.Volume{
+		Name: shmVolumeName,
+		VolumeSource: v1.VolumeSource{
+			EmptyDir: &v1.EmptyDirVolumeSource{
+				Medium:    v1.StorageMediumMemory,
+				SizeLimit: &size,
+			},
+		},
+	})
+	container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+		Name:      shmVolumeName,
+		MountPath: shmMountPath,
+	})
+
+	return nil
+}
+
 func mergeProbe(src *a1.Probe, dst *v1.Probe) {
 	if src == nil {
 		return
@@ -26,3 +114,55 @@ func mergeProbe(src *a1.Probe, dst *v1.Probe) {
 		dst.FailureThreshold = src.FailureThreshold
 	}
 }
+
+// engineConfigVolume projects the engineConfigFile of each model from its
+// AIModelMap's ConfigMap, or the AIDeployment's for inline models, into a
+// volume. Each file is put in engineConfigDir and named after the model's
+// HostName plus ext. It returns nil if no model has a config file.
+func engineConfigVolume(engine a1.AIEngineName, models []aimodelmap.ResolvedModel, ext string) (*v1.Volume, error) {
+	sources := []v1.VolumeProjection{}
+	inline := false
+
+	for _, m := range models {
+		if m.Spec.EngineConfigFile == "" {
+			continue
+		}
+
+		configMap := m.Name
+		if m.Variant == aimodelmap.InlineVariant {
+			// Inline models share a HostName so their files would overwrite each other
+			if inline {
+				return nil, fmt.Errorf("only one inline model can have an engine config file, use an AIModelMap for the others")
+			}
+			inline = true
+			configMap = aimodelmap.InlineConfigMapName(m.Name)
+		}
+
+		sources = append(sources, v1.VolumeProjection{
+			ConfigMap: &v1.ConfigMapProjection{
+				LocalObjectReference: v1.LocalObjectReference{
+					Name: configMap,
+				},
+				Items: []v1.KeyToPath{
+					{
+						Key:  aimodelmap.FmtConfigMapKey(engine, m.Variant, constants.AIModelMapSpecEngineConfig),
+						Path: engineConfigDir + "/" + m.HostName + ext,
+					},
+				},
+			},
+		})
+	}
+
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	return &v1.Volume{
+		Name: engineConfigVolumeName,
+		VolumeSource: v1.VolumeSource{
+			Projected: &v1.ProjectedVolumeSource{
+				Sources: sources,
+			},
+		},
+	}, nil
+}
@@ -2,12 +2,15 @@ package engines
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
 	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
 	"github.com/premAI-io/prem-operator/controllers/aideployment"
 	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
 	"github.com/premAI-io/prem-operator/controllers/constants"
-	"github.com/premAI-io/prem-operator/controllers/resources"
+	"github.com/premAI-io/prem-operator/pkg/downloader"
 	"github.com/premAI-io/prem-operator/pkg/utils"
 	log "github.com/sirupsen/logrus"
 	appsv1 "k8s.io/api/apps/v1"
@@ -18,15 +21,40 @@ import (
 
 const (
 	vllmContainerVolumePath = "/root/.cache/huggingface"
+	vllmPort                = int32(8000)
+	vllmAdaptersVolume      = "adapters"
+	vllmAdaptersPath        = "/adapters"
+	vllmAdapterInitPrefix   = "init-adapter-"
 )
 
+// vllmAdapterScript downloads a LoRA adapter from the Hugging Face Hub into
+// $ADAPTER_DIR. Adapters with a URI the operator's downloader supports are
+// fetched by it instead, a single file is a tar archive of the adapter.
+const vllmAdapterScript = `set -e
+mkdir -p "$ADAPTER_DIR"
+huggingface-cli download "$ADAPTER_URI" --local-dir "$ADAPTER_DIR"`
+
 const (
 	vllmImageFormat = "%s:%s"
 )
 
+// vllmRayLeaderScript starts the Ray head, waits for the workers of the group
+// to join it and then starts the server with the container's args
+const vllmRayLeaderScript = `set -e
+ray start --head --port=6379
+until [ "$(python3 -c 'import ray; ray.init(address="auto"); print(len([n for n in ray.nodes() if n["Alive"]]))')" -ge "$GROUP_SIZE" ]; do
+  echo "waiting for the workers of the group to join"
+  sleep 5
+done
+exec python3 -m vllm.entrypoints.openai.api_server "$@"`
+
+// vllmRayWorkerScript joins the Ray cluster of the group's leader
+const vllmRayWorkerScript = `ray start --address="$LEADER_ADDRESS:6379" --block`
+
 var (
 	ErrModelsNotSpecified = fmt.Errorf("models not specified")
 	ErrorOnlyOneModel     = fmt.Errorf("only one model can be specified")
+	ErrorOnlyOneBaseModel = fmt.Errorf("only one model can be specified which is not an adapter")
 )
 
 type vllmAi struct {
@@ -43,18 +71,65 @@ type vllmAi struct {
 	// used to customize the deployment
 	deploymentOptions *a1.AIDeployment
 	model             aimodelmap.ResolvedModel
+	// LoRA adapters of the model
+	adapters []aimodelmap.ResolvedModel
 }
 
-func NewVllmAi(ai *a1.AIDeployment, models []aimodelmap.ResolvedModel) (aideployment.MLEngine, error) {
+// validateVllmModels checks there is one base model and the rest are its
+// adapters. Adapters are served under their HostName so these must differ.
+func validateVllmModels(_ *a1.AIDeployment, models []aimodelmap.ResolvedModel) error {
 	if len(models) == 0 {
-		return nil, ErrModelsNotSpecified
+		return ErrModelsNotSpecified
+	}
+
+	bases := 0
+	var base aimodelmap.ResolvedModel
+	for _, m := range models {
+		if m.Spec.AdapterOf == "" {
+			base = m
+			bases++
+		}
+	}
+	if bases != 1 {
+		return ErrorOnlyOneBaseModel
+	}
+
+	names := map[string]bool{}
+	for _, m := range models {
+		if m.Spec.AdapterOf == "" {
+			continue
+		}
+
+		if m.Spec.AdapterOf != base.Spec.Uri {
+			return fmt.Errorf("adapter %s is for %s, but the base model is %s", m.HostName, m.Spec.AdapterOf, base.Spec.Uri)
+		}
+		if m.Spec.Uri == "" {
+			return fmt.Errorf("adapter %s has no URI", m.HostName)
+		}
+		if names[m.HostName] {
+			return fmt.Errorf("more than one adapter is named %s, use a modelMapRef for each adapter", m.HostName)
+		}
+		names[m.HostName] = true
 	}
 
-	if len(models) > 1 {
-		return nil, ErrorOnlyOneModel
+	return nil
+}
+
+func NewVllmAi(ai *a1.AIDeployment, models []aimodelmap.ResolvedModel) (aideployment.MLEngine, error) {
+	if err := validateVllmModels(ai, models); err != nil {
+		return nil, err
+	}
+
+	var model aimodelmap.ResolvedModel
+	adapters := []aimodelmap.ResolvedModel{}
+	for _, m := range models {
+		if m.Spec.AdapterOf == "" {
+			model = m
+		} else {
+			adapters = append(adapters, m)
+		}
 	}
 
-	model := models[0]
 	imageTag := "latest"
 	imageRepo := constants.ImageRepositoryVllm
 	if ai.Spec.Engine.Options[constants.ImageTagKey] != "" {
@@ -73,11 +148,67 @@ func NewVllmAi(ai *a1.AIDeployment, models []aimodelmap.ResolvedModel) (aideploy
 		engineEnvVars:     ai.Spec.Env,
 		deploymentOptions: ai,
 		model:             model,
+		adapters:          adapters,
 	}, nil
 }
 
+func init() {
+	Register(Definition{
+		Name:          a1.AIEngineNameVLLM,
+		ModelMapKey:   a1.AIModelMapKeyVllm,
+		DefaultPort:   vllmPort,
+		New:           NewVllmAi,
+		Validate:      validateVllmModels,
+		ModelCache:    true,
+		ModelPrefetch: true,
+	})
+}
+
 func (v *vllmAi) Port() int32 {
-	return 8000
+	return vllmPort
+}
+
+// MultiNode runs the group as a Ray cluster with the leader as its head. The
+// leader splits the model's layers across the pods with pipeline parallelism.
+func (v *vllmAi) MultiNode(leader *v1.PodTemplateSpec, size int32) (*v1.PodTemplateSpec, error) {
+	var container *v1.Container
+	for i := range leader.Spec.Containers {
+		if leader.Spec.Containers[i].Name == constants.ContainerEngineName {
+			container = &leader.Spec.Containers[i]
+		}
+	}
+	if container == nil {
+		return nil, fmt.Errorf("vllm pod template has no %s container", constants.ContainerEngineName)
+	}
+
+	worker := leader.DeepCopy()
+
+	container.Command = []string{"sh", "-c", vllmRayLeaderScript, "vllm-leader"}
+	container.Args = append(container.Args,
+		"--distributed-executor-backend", "ray",
+		"--pipeline-parallel-size", strconv.Itoa(int(size)),
+	)
+
+	for i := range worker.Spec.Containers {
+		c := &worker.Spec.Containers[i]
+		if c.Name != constants.ContainerEngineName {
+			continue
+		}
+
+		// Workers don't serve anything, the leader reports readiness for the group
+		c.Command = []string{"sh", "-c", vllmRayWorkerScript}
+		c.Args = nil
+		c.Ports = nil
+		c.StartupProbe = nil
+		c.ReadinessProbe = nil
+		c.LivenessProbe = nil
+	}
+	// Adapters are only loaded by the leader
+	worker.Spec.InitContainers = slices.DeleteFunc(worker.Spec.InitContainers, func(c v1.Container) bool {
+		return strings.HasPrefix(c.Name, vllmAdapterInitPrefix)
+	})
+
+	return worker, nil
 }
 
 func (v *vllmAi) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
@@ -93,7 +224,7 @@ func (v *vllmAi) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
 		ImagePullPolicy: v1.PullAlways,
 		Name:            constants.ContainerEngineName,
 		Image:           v.engineImage,
-		Env:             v.engineEnvVars,
+		Env:             hfTokenEnv(v.model.Spec, v.engineEnvVars),
 		VolumeMounts: []v1.VolumeMount{
 			{
 				Name:      "models",
@@ -101,7 +232,7 @@ func (v *vllmAi) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
 			},
 		},
 		Args: []string{
-			"--model", v.model.Spec.Uri,
+			"--model", modelPath(v.deploymentOptions, v.model),
 		},
 		StartupProbe: &v1.Probe{
 			InitialDelaySeconds: 3,
@@ -121,6 +252,11 @@ func (v *vllmAi) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
 		},
 	}
 
+	// Clients refer to a downloaded model by its host name rather than its path
+	if modelPath(v.deploymentOptions, v.model) != v.model.Spec.Uri {
+		container.Args = append(container.Args, "--served-model-name", v.model.HostName)
+	}
+
 	engineOpts := make(map[string]string)
 	if v.model.Spec.DataType != "" {
 		engineOpts[constants.DtypeKey] = string(v.model.Spec.DataType)
@@ -146,52 +282,82 @@ func (v *vllmAi) Deployment(owner metav1.Object) (*appsv1.Deployment, error) {
 		}
 	}
 
+	tp, err := tensorParallelSize(v.deploymentOptions)
+	if err != nil {
+		return nil, err
+	}
+	if tp > 1 {
+		container.Args = append(container.Args, "--tensor-parallel-size", strconv.FormatInt(tp, 10))
+	}
+
+	initContainers := []v1.Container{}
+	volumes := []v1.Volume{
+		{
+			Name: "models",
+			VolumeSource: v1.VolumeSource{
+				EmptyDir: &v1.EmptyDirVolumeSource{},
+			},
+		},
+	}
+
+	if len(v.adapters) > 0 {
+		adaptersMount := v1.VolumeMount{
+			Name:      vllmAdaptersVolume,
+			MountPath: vllmAdaptersPath,
+		}
+		container.VolumeMounts = append(container.VolumeMounts, adaptersMount)
+		volumes = append(volumes, v1.Volume{
+			Name: vllmAdaptersVolume,
+			VolumeSource: v1.VolumeSource{
+				EmptyDir: &v1.EmptyDirVolumeSource{},
+			},
+		})
+
+		container.Args = append(container.Args, "--enable-lora", "--lora-modules")
+		for i, a := range v.adapters {
+			dir := fmt.Sprintf("%s/%s", vllmAdaptersPath, a.HostName)
+			container.Args = append(container.Args, fmt.Sprintf("%s=%s", a.HostName, dir))
+
+			name := fmt.Sprintf("%s%d", vllmAdapterInitPrefix, i)
+			if downloader.Supported(a.Spec.Uri) {
+				initContainers = append(initContainers, downloadContainer(
+					v.deploymentOptions.Spec.Env, name, a.Spec, dir, !downloader.IsDirectory(a.Spec.Uri), adaptersMount,
+				))
+				continue
+			}
+
+			initContainers = append(initContainers, v1.Container{
+				ImagePullPolicy: v1.PullIfNotPresent,
+				Name:            name,
+				Image:           v.engineImage,
+				Command:         []string{"sh", "-c"},
+				Args:            []string{vllmAdapterScript},
+				Env: append([]v1.EnvVar{
+					{Name: "ADAPTER_DIR", Value: dir},
+					{Name: "ADAPTER_URI", Value: a.Spec.Uri},
+				}, hfTokenEnv(a.Spec, v.engineEnvVars)...),
+				VolumeMounts: []v1.VolumeMount{adaptersMount},
+			})
+		}
+	}
+
 	mergeProbe(v.deploymentOptions.Spec.Deployment.StartupProbe, container.StartupProbe)
 	mergeProbe(v.deploymentOptions.Spec.Deployment.ReadinessProbe, container.ReadinessProbe)
 	mergeProbe(v.deploymentOptions.Spec.Deployment.LivenessProbe, container.LivenessProbe)
 
-	serviceAccount := false
-	replicas := int32(1)
-	if v.deploymentOptions.Spec.Deployment.Replicas != nil {
-		replicas = *v.deploymentOptions.Spec.Deployment.Replicas
+	deployment := newDeployment(v.deploymentOptions, owner)
+	pod := &deployment.Spec.Template.Spec
+	pod.Volumes = append(pod.Volumes, volumes...)
+
+	if err := addSharedMemory(v.deploymentOptions, tp, pod, &container); err != nil {
+		return nil, err
 	}
-	deployment := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:            v.resourceName,
-			Namespace:       v.namespace,
-			OwnerReferences: resources.GenOwner(owner),
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: &replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: resources.GenDefaultLabels(v.resourceName),
-			},
-			Template: v1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: utils.MergeMaps(
-						resources.GenDefaultLabels(v.resourceName),
-						v.deploymentOptions.Spec.Deployment.Labels,
-					),
-					Annotations: utils.MergeMaps(
-						v.deploymentOptions.Spec.Deployment.Annotations,
-					),
-				},
-				Spec: v1.PodSpec{
-					Containers:                   []v1.Container{},
-					AutomountServiceAccountToken: &serviceAccount,
-					Volumes: []v1.Volume{
-						{
-							Name: "models",
-							VolumeSource: v1.VolumeSource{
-								EmptyDir: &v1.EmptyDirVolumeSource{},
-							},
-						},
-					},
-				},
-			},
-		},
+
+	initContainers = append(addModelDownload(v.deploymentOptions, v.model, pod, &container), initContainers...)
+
+	if err := addEngineContainers(v.deploymentOptions, pod, container, initContainers...); err != nil {
+		return nil, err
 	}
 
-	deployment.Spec.Template.Spec.Containers = append(deployment.Spec.Template.Spec.Containers, container)
 	return deployment, nil
 }
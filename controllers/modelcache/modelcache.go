@@ -0,0 +1,176 @@
+// Package modelcache names the things model caches are made of. For a
+// ModelCache these are the claim the models are stored in, the directory of
+// each model and the Job which downloads it. For an AIModelPrefetch they are
+// the directory on the node and the node label which says a model is there.
+package modelcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"path"
+	"strings"
+
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/pkg/downloader"
+	"github.com/premAI-io/prem-operator/pkg/utils"
+)
+
+const (
+	// MountPath is where the cache is mounted in the engine's pod and in
+	// the Jobs which populate it
+	MountPath = "/model-cache"
+
+	// PrefetchPath is where an AIModelPrefetch downloads models to on the
+	// node, it is mounted at the same path
+	PrefetchPath = PrefetchParent + "/models"
+	// PrefetchParent is the directory PrefetchPath is in. Engine pods mount
+	// it rather than PrefetchPath, so that they don't create PrefetchPath,
+	// owned by root, on nodes which don't have it.
+	PrefetchParent = "/var/lib/prem-operator"
+	// PrefetchLabelPrefix is the prefix of the node labels which say a
+	// model has been prefetched onto the node, see NodeLabel
+	PrefetchLabelPrefix = "prefetch.mlcontroller.premlabs.io/"
+
+	// RefIndexKey is the field index of AIDeployments by the ModelCache
+	// they refer to
+	RefIndexKey = ".spec.modelCacheRef"
+
+	// Job names are used as label values, which are limited to 63 characters
+	maxNameLength = 63
+	hashLength    = 12
+)
+
+// ClaimName is the name of the PersistentVolumeClaim of a ModelCache
+func ClaimName(cache string) string {
+	return cache
+}
+
+// Cacheable is true if m can be stored in a ModelCache. That is if the
+// downloader can fetch it and it isn't an adapter, which engines download
+// alongside the base model.
+func Cacheable(m aimodelmap.ResolvedModel) bool {
+	return downloader.Supported(m.Spec.Uri) && m.Spec.AdapterOf == ""
+}
+
+// Revision is what the model's URI is pinned to, the revision given in the
+// URI if there is one, otherwise its sha256
+func Revision(m aimodelmap.ResolvedModel) string {
+	if r := downloader.Revision(m.Spec.Uri); r != "" {
+		return r
+	}
+
+	return m.Spec.Sha256
+}
+
+// Key identifies m in the cache, it is the name of the directory m is stored
+// in. It is made from the model's host name and a hash of its name, variant,
+// URI, revision and sha256, so a model which changes is downloaded again.
+func Key(m aimodelmap.ResolvedModel) string {
+	h := sha256.New()
+	for _, s := range []string{m.Name, m.Variant, m.Spec.Uri, Revision(m), m.Spec.Sha256} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+
+	return join(m.HostName, hex.EncodeToString(h.Sum(nil)), maxNameLength-hashLength-1)
+}
+
+// Dir is the directory m is in when the cache is mounted
+func Dir(m aimodelmap.ResolvedModel) string {
+	return MountPath + "/" + Key(m)
+}
+
+// FileName is the name of the file at m's URI, which is the last element of
+// its path. A model which is stored as a single file is in Dir under it.
+func FileName(m aimodelmap.ResolvedModel) string {
+	u, err := url.Parse(m.Spec.Uri)
+	if err != nil {
+		return ""
+	}
+
+	return path.Base(u.Path)
+}
+
+// SubPath is where m is in the cache's claim, the path Dir is mounted from.
+// With file it is the model's file, see FileName, otherwise its directory.
+func SubPath(m aimodelmap.ResolvedModel, file bool) string {
+	if file {
+		return Key(m) + "/" + FileName(m)
+	}
+
+	return Key(m)
+}
+
+// PrefetchDir is the directory on the node an AIModelPrefetch downloads m to
+func PrefetchDir(m aimodelmap.ResolvedModel) string {
+	return PrefetchPath + "/" + Key(m)
+}
+
+// NodeLabel is the label set on the nodes m has been prefetched onto
+func NodeLabel(m aimodelmap.ResolvedModel) string {
+	return PrefetchLabelPrefix + Key(m)
+}
+
+// NodeLabelValue is the value of the node labels set by the AIModelPrefetch
+// with name, it is how the AIModelPrefetch finds the labels it has to remove
+func NodeLabelValue(name string) string {
+	h := sha256.Sum256([]byte(name))
+	return join(name, hex.EncodeToString(h[:]), maxNameLength)
+}
+
+// JobName is the name of the Job which downloads the model with key into
+// the cache
+func JobName(cache, key string) string {
+	h := sha256.Sum256([]byte(cache + "/" + key))
+	return join(cache, hex.EncodeToString(h[:]), maxNameLength)
+}
+
+// join appends a hash to name, truncating name so the result is at most max
+// characters
+func join(name, hash string, max int) string {
+	name = utils.ToHostName(name)
+	if len(name) > max-hashLength-1 {
+		name = strings.TrimRight(name[:max-hashLength-1], "-")
+	}
+
+	return name + "-" + hash[:hashLength]
+}
+
+// Entry is the status of m before the cache has seen it
+func Entry(m aimodelmap.ResolvedModel) a1.CachedModel {
+	return a1.CachedModel{
+		Key:      Key(m),
+		Name:     m.Name,
+		Variant:  m.Variant,
+		Uri:      m.Spec.Uri,
+		Revision: Revision(m),
+		Phase:    a1.CachedModelPhasePending,
+	}
+}
+
+// Find returns the status of the model with key, or nil if the cache hasn't
+// seen it
+func Find(cache *a1.ModelCache, key string) *a1.CachedModel {
+	for i := range cache.Status.Models {
+		if cache.Status.Models[i].Key == key {
+			return &cache.Status.Models[i]
+		}
+	}
+
+	return nil
+}
+
+// IndexModelCacheRef extracts the ModelCache an AIDeployment refers to, it
+// is meant to be passed to a FieldIndexer
+func IndexModelCacheRef(obj ctrlClient.Object) []string {
+	d, ok := obj.(*a1.AIDeployment)
+	if !ok || d.Spec.ModelCacheRef == nil || d.Spec.ModelCacheRef.Name == "" {
+		return nil
+	}
+
+	return []string{d.Spec.ModelCacheRef.Name}
+}
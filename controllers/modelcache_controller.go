@@ -0,0 +1,395 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/engines"
+	"github.com/premAI-io/prem-operator/controllers/modelcache"
+	"github.com/premAI-io/prem-operator/controllers/resources"
+)
+
+// ModelCacheReconciler reconciles a ModelCache object
+type ModelCacheReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// cacheUse is a model which AIDeployments load from the cache
+type cacheUse struct {
+	model aimodelmap.ResolvedModel
+	// The first AIDeployment to use the model, its env is given to the Job
+	// which downloads it
+	deployment  *a1.AIDeployment
+	deployments []string
+	// The model is stored as a single file for the first AIDeployment's
+	// engine, see engines.Definition.CachedAsFile
+	file bool
+}
+
+//+kubebuilder:rbac:groups=premlabs.io,resources=modelcaches,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=premlabs.io,resources=modelcaches/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=premlabs.io,resources=modelcaches/finalizers,verbs=update
+//+kubebuilder:rbac:groups=premlabs.io,resources=aideployments,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+
+// Reconcile creates the cache's claim and a Job for each model the
+// AIDeployments using the cache need, then records the state of the Jobs in
+// the status. A model stays in the status while its Job exists, deleting the
+// Job causes the model to be checked, and downloaded again if needed, the
+// next time it is used.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.17.1/pkg/reconcile
+func (r *ModelCacheReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	lg := log.FromContext(ctx)
+
+	cache := &a1.ModelCache{}
+	if err := r.Get(ctx, req.NamespacedName, cache); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if err := r.applyClaim(ctx, cache); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	uses, err := r.findUses(ctx, cache)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	selector := client.MatchingLabels{constants.ModelCacheLabel: cache.Name}
+	jobList := &batchv1.JobList{}
+	if err := r.List(ctx, jobList, client.InNamespace(cache.Namespace), selector); err != nil {
+		return ctrl.Result{}, err
+	}
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, client.InNamespace(cache.Namespace), selector); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	jobs := map[string]*batchv1.Job{}
+	for i, j := range jobList.Items {
+		jobs[j.Labels[constants.ModelCacheKeyLabel]] = &jobList.Items[i]
+	}
+	pods := map[string][]corev1.Pod{}
+	for _, p := range podList.Items {
+		key := p.Labels[constants.ModelCacheKeyLabel]
+		pods[key] = append(pods[key], p)
+	}
+
+	status := cache.Status.DeepCopy()
+	status.ObservedGeneration = cache.Generation
+	status.Models = []a1.CachedModel{}
+
+	for key, use := range uses {
+		job := jobs[key]
+		if job == nil {
+			job = engines.ModelCacheJob(cache, use.deployment, use.model, use.file)
+			job.OwnerReferences = modelCacheOwner(cache)
+
+			lg.Info("Creating Job to download model into ModelCache", "Name", job.Name, "Uri", use.model.Spec.Uri)
+			if err := r.Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+				return ctrl.Result{}, err
+			}
+		}
+
+		entry := modelcache.Entry(use.model)
+		old := modelcache.Find(cache, key)
+		if old != nil {
+			entry.Bytes = old.Bytes
+			entry.Hits = old.Hits
+
+			// A hit is an AIDeployment which didn't have to wait for the model
+			for _, d := range use.deployments {
+				if old.Phase == a1.CachedModelPhaseReady && !slices.Contains(old.Deployments, d) {
+					entry.Hits++
+				}
+			}
+		}
+		entry.Deployments = use.deployments
+
+		setCachedModelPhase(&entry, job, pods[key])
+		status.Models = append(status.Models, entry)
+	}
+
+	// The models no AIDeployment uses are still stored
+	for key, job := range jobs {
+		old := modelcache.Find(cache, key)
+		if _, used := uses[key]; used || old == nil {
+			continue
+		}
+
+		entry := *old.DeepCopy()
+		entry.Deployments = nil
+		setCachedModelPhase(&entry, job, pods[key])
+		status.Models = append(status.Models, entry)
+	}
+
+	sort.Slice(status.Models, func(i, j int) bool { return status.Models[i].Key < status.Models[j].Key })
+
+	status.Hits = 0
+	status.BytesStored = 0
+	for _, m := range status.Models {
+		status.Hits += m.Hits
+		status.BytesStored += m.Bytes
+	}
+
+	if equality.Semantic.DeepEqual(status, &cache.Status) {
+		return ctrl.Result{}, nil
+	}
+
+	cache.Status = *status
+	err = r.Status().Update(ctx, cache)
+	if apierrors.IsConflict(err) {
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	return ctrl.Result{}, err
+}
+
+// applyClaim creates the cache's PersistentVolumeClaim, or resizes it
+func (r *ModelCacheReconciler) applyClaim(ctx context.Context, cache *a1.ModelCache) error {
+	accessMode := cache.Spec.AccessMode
+	if accessMode == "" {
+		accessMode = corev1.ReadWriteMany
+	}
+
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            modelcache.ClaimName(cache.Name),
+			Namespace:       cache.Namespace,
+			OwnerReferences: modelCacheOwner(cache),
+			Labels: map[string]string{
+				constants.ModelCacheLabel: cache.Name,
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{accessMode},
+			StorageClassName: cache.Spec.StorageClassName,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: cache.Spec.Size,
+				},
+			},
+		},
+	}
+
+	if _, err := resources.Apply(ctx, r.Client, claim); err != nil {
+		return fmt.Errorf("applying PersistentVolumeClaim %s: %w", claim.Name, err)
+	}
+
+	return nil
+}
+
+// findUses resolves the models of the AIDeployments using the cache and
+// returns those which can be cached by their key. AIDeployments which can't
+// be resolved are skipped, their status says why.
+func (r *ModelCacheReconciler) findUses(ctx context.Context, cache *a1.ModelCache) (map[string]*cacheUse, error) {
+	deployments := &a1.AIDeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(cache.Namespace), client.MatchingFields{
+		modelcache.RefIndexKey: cache.Name,
+	}); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(deployments.Items, func(i, j int) bool {
+		return deployments.Items[i].Name < deployments.Items[j].Name
+	})
+
+	uses := map[string]*cacheUse{}
+	for i := range deployments.Items {
+		ai := &deployments.Items[i]
+		if ai.DeletionTimestamp != nil {
+			continue
+		}
+
+		engine, err := engines.Get(ctx, r.Client, ai)
+		if err != nil || !engine.ModelCache {
+			continue
+		}
+
+		models, err := aimodelmap.Resolve(ai, engine.ModelMapKey, ctx, r.Client)
+		if err != nil {
+			continue
+		}
+
+		for _, m := range models {
+			if !modelcache.Cacheable(m) {
+				continue
+			}
+
+			key := modelcache.Key(m)
+			use, ok := uses[key]
+			if !ok {
+				use = &cacheUse{model: m, deployment: ai, file: engine.CachedAsFile(m)}
+				uses[key] = use
+			}
+			if !slices.Contains(use.deployments, ai.Name) {
+				use.deployments = append(use.deployments, ai.Name)
+			}
+		}
+	}
+
+	return uses, nil
+}
+
+// setCachedModelPhase sets the phase of a model from its Job. The size of a
+// downloaded model is read from the termination message of the Job's pod.
+func setCachedModelPhase(entry *a1.CachedModel, job *batchv1.Job, pods []corev1.Pod) {
+	entry.Message = ""
+
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			entry.Phase = a1.CachedModelPhaseFailed
+			entry.Message = c.Message
+			if msg := terminationMessage(pods, corev1.PodFailed); msg != "" {
+				entry.Message = fmt.Sprintf("%s: %s", c.Message, msg)
+			}
+			return
+		}
+	}
+
+	switch {
+	case job.Status.Succeeded > 0:
+		entry.Phase = a1.CachedModelPhaseReady
+		if entry.Bytes == 0 {
+			entry.Bytes, _ = strconv.ParseInt(terminationMessage(pods, corev1.PodSucceeded), 10, 64)
+		}
+	case job.Status.Active > 0:
+		entry.Phase = a1.CachedModelPhaseDownloading
+	default:
+		entry.Phase = a1.CachedModelPhasePending
+	}
+}
+
+// terminationMessage is the message of the last pod in phase to terminate
+func terminationMessage(pods []corev1.Pod, phase corev1.PodPhase) string {
+	var (
+		msg  string
+		last metav1.Time
+	)
+
+	for _, p := range pods {
+		if p.Status.Phase != phase {
+			continue
+		}
+
+		for _, s := range p.Status.ContainerStatuses {
+			if t := s.State.Terminated; t != nil && !t.FinishedAt.Before(&last) {
+				msg = strings.TrimSpace(t.Message)
+				last = t.FinishedAt
+			}
+		}
+	}
+
+	return msg
+}
+
+func modelCacheOwner(cache *a1.ModelCache) []metav1.OwnerReference {
+	return []metav1.OwnerReference{
+		*metav1.NewControllerRef(cache, schema.GroupVersionKind{
+			Group:   a1.GroupVersion.Group,
+			Version: a1.GroupVersion.Version,
+			Kind:    "ModelCache",
+		}),
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager. A ModelCache is
+// reconciled when the AIDeployments using it, or the AIModelMaps they refer
+// to, change. It uses the field indexes of AIDeployments registered by the
+// AIDeploymentReconciler.
+func (r *ModelCacheReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&a1.ModelCache{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&corev1.PersistentVolumeClaim{}).
+		Owns(&batchv1.Job{}).
+		Watches(
+			&a1.AIDeployment{},
+			handler.EnqueueRequestsFromMapFunc(r.findCacheForDeployment),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(
+			&a1.AIModelMap{},
+			handler.EnqueueRequestsFromMapFunc(r.findCachesForModelMap),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Complete(r)
+}
+
+// findCacheForDeployment returns the ModelCache an AIDeployment uses
+func (r *ModelCacheReconciler) findCacheForDeployment(_ context.Context, obj client.Object) []reconcile.Request {
+	requests := []reconcile.Request{}
+	for _, name := range modelcache.IndexModelCacheRef(obj) {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: name},
+		})
+	}
+
+	return requests
+}
+
+// findCachesForModelMap lists the ModelCaches used by the AIDeployments
+// which reference an AIModelMap
+func (r *ModelCacheReconciler) findCachesForModelMap(ctx context.Context, obj client.Object) []reconcile.Request {
+	deployments := &a1.AIDeploymentList{}
+	if err := r.List(ctx, deployments, client.MatchingFields{
+		aimodelmap.ModelMapRefIndexKey: aimodelmap.RefIndexValue(obj.GetNamespace(), obj.GetName()),
+	}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list AIDeployments referencing AIModelMap", "name", obj.GetName())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for i := range deployments.Items {
+		for _, req := range r.findCacheForDeployment(ctx, &deployments.Items[i]) {
+			if !slices.Contains(requests, req) {
+				requests = append(requests, req)
+			}
+		}
+	}
+
+	return requests
+}
@@ -0,0 +1,170 @@
+package resources
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"slices"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/csaupgrade"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/premAI-io/prem-operator/controllers/constants"
+)
+
+const (
+	// SpecHashAnnotation holds a hash of the object as it was rendered by the
+	// operator, if it matches then the object may not need to be applied
+	SpecHashAnnotation = "mlcontroller.premlabs.io/spec-hash"
+)
+
+// legacyFieldManagers are the names the operator had when it used Create and
+// Update. Their fields are handed over to constants.FieldManager, otherwise
+// the operator would conflict with itself.
+var legacyFieldManagers = sets.New("manager")
+
+// Apply creates or updates obj with server-side apply. Only the fields set
+// in obj are owned by the operator, so fields set by other controllers are
+// left alone. Nothing is written if the existing object was applied by the
+// operator from the same rendered obj and still has every value set in it.
+// Otherwise obj is applied, so changes made by others to the fields the
+// operator owns are reverted.
+//
+// On return obj holds the object as it is on the server. The returned bool is
+// true if obj was written.
+func Apply(ctx context.Context, c ctrlClient.Client, obj ctrlClient.Object) (bool, error) {
+	gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+	if err != nil {
+		return false, err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+
+	hash, err := specHash(obj)
+	if err != nil {
+		return false, err
+	}
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[SpecHashAnnotation] = hash
+	obj.SetAnnotations(annotations)
+
+	existing := obj.DeepCopyObject().(ctrlClient.Object)
+	err = c.Get(ctx, ctrlClient.ObjectKeyFromObject(obj), existing)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, err
+	}
+
+	resourceVersion := ""
+	if err == nil {
+		unchanged, err := applied(obj, existing, hash)
+		if err != nil {
+			return false, err
+		}
+
+		if unchanged {
+			reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(existing).Elem())
+			return false, nil
+		}
+
+		patch, err := csaupgrade.UpgradeManagedFieldsPatch(existing, legacyFieldManagers, constants.FieldManager)
+		if err != nil {
+			return false, err
+		}
+
+		if patch != nil {
+			if err := c.Patch(ctx, existing, ctrlClient.RawPatch(types.JSONPatchType, patch)); err != nil {
+				return false, err
+			}
+		}
+
+		resourceVersion = existing.GetResourceVersion()
+	}
+
+	if err := c.Patch(ctx, obj, ctrlClient.Apply, ctrlClient.FieldOwner(constants.FieldManager)); err != nil {
+		return false, err
+	}
+
+	return obj.GetResourceVersion() != resourceVersion, nil
+}
+
+func specHash(obj ctrlClient.Object) (string, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// applied is true if existing was applied from obj, whose hash is given, and
+// hasn't drifted since. That is if the operator still has a managed fields
+// entry for it, which another manager may have taken over, and it still has
+// the values set in obj, which another manager may have changed.
+func applied(obj, existing ctrlClient.Object, hash string) (bool, error) {
+	if existing.GetAnnotations()[SpecHashAnnotation] != hash {
+		return false, nil
+	}
+
+	owned := slices.ContainsFunc(existing.GetManagedFields(), func(e metav1.ManagedFieldsEntry) bool {
+		return e.Manager == constants.FieldManager && e.Operation == metav1.ManagedFieldsOperationApply && e.Subresource == ""
+	})
+	if !owned {
+		return false, nil
+	}
+
+	config, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return false, err
+	}
+	live, err := runtime.DefaultUnstructuredConverter.ToUnstructured(existing)
+	if err != nil {
+		return false, err
+	}
+
+	return contains(live, config), nil
+}
+
+// contains is true if live has every value set in config. A list has to have
+// the same length, its items are compared in order.
+func contains(live, config any) bool {
+	switch config := config.(type) {
+	case nil:
+		return true
+	case map[string]any:
+		live, ok := live.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, v := range config {
+			if !contains(live[k], v) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		live, ok := live.([]any)
+		if !ok || len(live) != len(config) {
+			return false
+		}
+		for i := range config {
+			if !contains(live[i], config[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(live, config)
+	}
+}
@@ -0,0 +1,131 @@
+package resources_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/resources"
+)
+
+var _ = Describe("Apply", func() {
+	var c client.Client
+
+	desired := func() *appsv1.Deployment {
+		labels := map[string]string{"app": "engine"}
+
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "engine",
+				Namespace: "default",
+			},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Name:  "serving",
+							Image: "vllm/vllm-openai:latest",
+						}},
+					},
+				},
+			},
+		}
+	}
+
+	get := func() *appsv1.Deployment {
+		d := &appsv1.Deployment{}
+		Expect(c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "engine"}, d)).To(Succeed())
+		return d
+	}
+
+	BeforeEach(func() {
+		// The fake client doesn't track managed fields, so the object starts
+		// with the entry the API server would add
+		existing := desired()
+		existing.ManagedFields = []metav1.ManagedFieldsEntry{{
+			Manager:    constants.FieldManager,
+			Operation:  metav1.ManagedFieldsOperationApply,
+			APIVersion: "apps/v1",
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte("{}")},
+		}}
+		c = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(existing).Build()
+
+		_, err := resources.Apply(context.Background(), c, desired())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("doesn't write an unchanged object", func() {
+		resourceVersion := get().ResourceVersion
+
+		written, err := resources.Apply(context.Background(), c, desired())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(written).To(BeFalse())
+		Expect(get().ResourceVersion).To(Equal(resourceVersion))
+	})
+
+	It("writes a changed object", func() {
+		d := desired()
+		d.Spec.Template.Spec.Containers[0].Args = []string{"--tensor-parallel-size", "2"}
+
+		written, err := resources.Apply(context.Background(), c, d)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(written).To(BeTrue())
+		Expect(get().Spec.Template.Spec.Containers[0].Args).To(Equal(d.Spec.Template.Spec.Containers[0].Args))
+	})
+
+	It("applies again when another manager took the object over", func() {
+		live := get()
+		live.ManagedFields = []metav1.ManagedFieldsEntry{{
+			Manager:    "kubectl-edit",
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			APIVersion: "apps/v1",
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte("{}")},
+		}}
+		Expect(c.Update(context.Background(), live)).To(Succeed())
+
+		written, err := resources.Apply(context.Background(), c, desired())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(written).To(BeTrue())
+	})
+
+	It("reverts changes to the fields it owns", func() {
+		live := get()
+		live.Spec.Template.Spec.Containers[0].Image = "vllm/vllm-openai:edited"
+		Expect(c.Update(context.Background(), live)).To(Succeed())
+
+		written, err := resources.Apply(context.Background(), c, desired())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(written).To(BeTrue())
+		Expect(get().Spec.Template.Spec.Containers[0].Image).To(Equal("vllm/vllm-openai:latest"))
+	})
+
+	It("leaves the fields it doesn't set", func() {
+		replicas := int32(3)
+		live := get()
+		live.Spec.Replicas = &replicas
+		Expect(c.Update(context.Background(), live)).To(Succeed())
+
+		_, err := resources.Apply(context.Background(), c, desired())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(get().Spec.Replicas).To(HaveValue(BeEquivalentTo(3)))
+	})
+
+	It("returns the object as it is on the server", func() {
+		d := desired()
+		_, err := resources.Apply(context.Background(), c, d)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.ResourceVersion).To(Equal(get().ResourceVersion))
+	})
+})
@@ -8,12 +8,20 @@ import (
 	networkv1 "k8s.io/api/networking/v1"
 )
 
-func DesiredIngress(owner metav1.Object, name, namespace string, hostname []string, svcName string, port int, labels, annotations map[string]string, tls bool) *networkv1.Ingress {
+// IngressRoute sends requests for Host to Port of the Service
+type IngressRoute struct {
+	Host string
+	Port int32
+}
+
+func DesiredIngress(owner metav1.Object, name, namespace string, routes []IngressRoute, svcName string, labels, annotations map[string]string, tls bool) *networkv1.Ingress {
 	t := networkv1.PathType("Prefix")
 	rules := []networkv1.IngressRule{}
-	for _, h := range hostname {
+	hostname := []string{}
+	for _, r := range routes {
+		hostname = append(hostname, r.Host)
 		rules = append(rules, networkv1.IngressRule{
-			Host: h,
+			Host: r.Host,
 			IngressRuleValue: networkv1.IngressRuleValue{
 				HTTP: &networkv1.HTTPIngressRuleValue{
 					Paths: []networkv1.HTTPIngressPath{{
@@ -22,7 +30,7 @@ func DesiredIngress(owner metav1.Object, name, namespace string, hostname []stri
 						Backend: networkv1.IngressBackend{
 							Service: &networkv1.IngressServiceBackend{
 								Name: svcName,
-								Port: networkv1.ServiceBackendPort{Number: int32(port)},
+								Port: networkv1.ServiceBackendPort{Number: r.Port},
 							},
 						},
 					}},
@@ -3,7 +3,6 @@ package resources
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 var (
@@ -11,13 +10,7 @@ var (
 	KubeGenericLabelPrefix = "app.kubernetes.io"
 )
 
-func DesiredService(owner metav1.Object, name, namespace string, selector, labels, annotations map[string]string, port int32) *corev1.Service {
-	ports := []corev1.ServicePort{
-		{
-			Port: port, TargetPort: intstr.FromInt(int(port)),
-		},
-	}
-
+func DesiredService(owner metav1.Object, name, namespace string, selector, labels, annotations map[string]string, ports []corev1.ServicePort) *corev1.Service {
 	if labels == nil {
 		labels = map[string]string{}
 	}
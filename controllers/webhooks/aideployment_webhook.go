@@ -0,0 +1,230 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aideployment"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/engines"
+)
+
+//+kubebuilder:webhook:path=/validate-premlabs-io-v1alpha1-aideployment,mutating=false,failurePolicy=fail,sideEffects=None,groups=premlabs.io,resources=aideployments,verbs=create;update,versions=v1alpha1,name=vaideployment.premlabs.io,admissionReviewVersions=v1
+
+// Only the metadata of Secrets is read, to warn about missing credentials
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+// AIDeploymentValidator rejects AIDeployments which the controller would
+// fail to reconcile. It runs the same model resolution and engine setup as
+// the controller, but does not write anything.
+type AIDeploymentValidator struct {
+	Client client.Client
+	// APIReader looks up the Secrets models refer to without caching them,
+	// Client is used if it is nil
+	APIReader client.Reader
+}
+
+var _ admission.CustomValidator = &AIDeploymentValidator{}
+
+// SetupWebhookWithManager registers the validator with the manager's webhook server
+func (v *AIDeploymentValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&a1.AIDeployment{}).
+		WithValidator(v).
+		Complete()
+}
+
+func (v *AIDeploymentValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	ai, ok := obj.(*a1.AIDeployment)
+	if !ok {
+		return nil, fmt.Errorf("expected an AIDeployment but got a %T", obj)
+	}
+
+	return v.validate(ctx, ai)
+}
+
+func (v *AIDeploymentValidator) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	ai, ok := newObj.(*a1.AIDeployment)
+	if !ok {
+		return nil, fmt.Errorf("expected an AIDeployment but got a %T", newObj)
+	}
+
+	return v.validate(ctx, ai)
+}
+
+func (v *AIDeploymentValidator) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate resolves the models and renders the Deployment. A missing
+// AIModelMap is only a warning because it may be created afterwards, in that
+// case the engine can't be checked.
+func (v *AIDeploymentValidator) validate(ctx context.Context, ai *a1.AIDeployment) (admission.Warnings, error) {
+	// The engines modify the AIDeployment they are given
+	ai = ai.DeepCopy()
+
+	var (
+		warnings admission.Warnings
+		errs     field.ErrorList
+		models   []aimodelmap.ResolvedModel
+	)
+
+	specPath := field.NewPath("spec")
+
+	engine, err := engines.Get(ctx, v.Client, ai)
+	if errors.Is(err, engines.ErrUnknownEngine) {
+		errs = append(errs, field.Invalid(specPath.Child("engine", "name"), ai.Spec.Engine.Name, fmt.Sprintf(
+			"must be one of %s or the name of an AIEngineTemplate", strings.Join(engineNames(), ", "),
+		)))
+		return warnings, invalid("AIDeployment", ai.Name, errs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	warnings = append(warnings, v.checkModelCache(ctx, ai, specPath.Child("modelCacheRef"))...)
+
+	for i := range ai.Spec.Models {
+		m := &ai.Spec.Models[i]
+		path := specPath.Child("models").Index(i)
+
+		if m.ModelMapRef == nil && m.EngineConfigFile != "" && engine.ValidateEngineConfig != nil {
+			if err := engine.ValidateEngineConfig(m.EngineConfigFile); err != nil {
+				errs = append(errs, invalidField(path.Child("engineConfigFile"), err))
+				continue
+			}
+		}
+
+		rm, err := aimodelmap.ResolveOne(m, ai, engine.ModelMapKey, ctx, v.Client)
+		if apierrors.IsNotFound(err) {
+			warnings = append(warnings, fmt.Sprintf("%s: %v", path.Child("modelMapRef"), err))
+			continue
+		}
+		if err != nil {
+			errs = append(errs, invalidField(path.Child("modelMapRef"), err))
+			continue
+		}
+
+		models = append(models, *rm)
+		warnings = append(warnings, v.checkCredentials(ctx, ai, rm, path)...)
+	}
+
+	if len(errs) > 0 || len(models) < len(ai.Spec.Models) {
+		return warnings, invalid("AIDeployment", ai.Name, errs)
+	}
+
+	mle, err := engine.Create(ai, models)
+	if err != nil {
+		errs = append(errs, invalidField(specPath.Child("engine"), err))
+		return warnings, invalid("AIDeployment", ai.Name, errs)
+	}
+
+	if deployment, err := aideployment.Render(ai, mle); err != nil {
+		errs = append(errs, invalidField(specPath.Child("deployment"), err))
+	} else if ai.Spec.Deployment.MultiNode != nil {
+		if _, err := aideployment.RenderMultiNode(ai, mle, deployment); err != nil {
+			errs = append(errs, invalidField(specPath.Child("deployment", "multiNode"), err))
+		}
+	}
+
+	for i, e := range ai.Spec.Endpoint {
+		if _, err := aideployment.EndpointPort(e, mle); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("endpoint").Index(i).Child("port"), e.Port, err.Error()))
+		}
+	}
+
+	return warnings, invalid("AIDeployment", ai.Name, errs)
+}
+
+// checkCredentials warns if the Secret a model's credentials are in doesn't
+// exist. The AIDeployment is accepted because the Secret may be created
+// afterwards, but its pods won't start until then.
+func (v *AIDeploymentValidator) checkCredentials(ctx context.Context, ai *a1.AIDeployment, m *aimodelmap.ResolvedModel, path *field.Path) admission.Warnings {
+	ref := m.Spec.CredentialsSecretRef
+	if ref == nil {
+		return nil
+	}
+
+	reader := v.APIReader
+	if reader == nil {
+		reader = v.Client
+	}
+
+	secret := &metav1.PartialObjectMetadata{}
+	secret.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+	err := reader.Get(ctx, client.ObjectKey{Namespace: ai.Namespace, Name: ref.Name}, secret)
+	if apierrors.IsNotFound(err) {
+		return admission.Warnings{fmt.Sprintf(
+			"%s: secret %s/%s not found, the pods won't start until it is created",
+			path.Child("credentialsSecretRef"), ai.Namespace, ref.Name,
+		)}
+	}
+	if err != nil {
+		return admission.Warnings{fmt.Sprintf("%s: couldn't check secret %s/%s: %v", path.Child("credentialsSecretRef"), ai.Namespace, ref.Name, err)}
+	}
+
+	return nil
+}
+
+// checkModelCache warns if the ModelCache the AIDeployment uses doesn't
+// exist, the Deployment isn't created until it does
+func (v *AIDeploymentValidator) checkModelCache(ctx context.Context, ai *a1.AIDeployment, path *field.Path) admission.Warnings {
+	if ai.Spec.ModelCacheRef == nil {
+		return nil
+	}
+
+	name := ai.Spec.ModelCacheRef.Name
+	err := v.Client.Get(ctx, client.ObjectKey{Namespace: ai.Namespace, Name: name}, &a1.ModelCache{})
+	if apierrors.IsNotFound(err) {
+		return admission.Warnings{fmt.Sprintf(
+			"%s: ModelCache %s/%s not found, the deployment won't be created until it is", path, ai.Namespace, name,
+		)}
+	}
+	if err != nil {
+		return admission.Warnings{fmt.Sprintf("%s: couldn't check ModelCache %s/%s: %v", path, ai.Namespace, name, err)}
+	}
+
+	return nil
+}
+
+func engineNames() []string {
+	defs := engines.Definitions()
+	names := make([]string, 0, len(defs))
+	for _, def := range defs {
+		names = append(names, string(def.Name))
+	}
+
+	return names
+}
+
+// invalidField reports err against path without repeating the, often large,
+// value of the field
+func invalidField(path *field.Path, err error) *field.Error {
+	return &field.Error{
+		Type:     field.ErrorTypeInvalid,
+		Field:    path.String(),
+		BadValue: field.OmitValueType{},
+		Detail:   err.Error(),
+	}
+}
+
+// invalid creates the error returned to the client, or nil if there are no errs
+func invalid(kind, name string, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(a1.GroupVersion.WithKind(kind).GroupKind(), name, errs)
+}
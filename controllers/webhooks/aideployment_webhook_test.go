@@ -0,0 +1,148 @@
+package webhooks_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/webhooks"
+)
+
+func newScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(a1.AddToScheme(scheme)).To(Succeed())
+	return scheme
+}
+
+func newClient(objs ...client.Object) client.Client {
+	return fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithIndex(&a1.AIDeployment{}, aimodelmap.ModelMapRefIndexKey, aimodelmap.IndexModelMapRefs).
+		WithObjects(objs...).
+		Build()
+}
+
+func newModelMap(variants ...a1.AIModelVariant) *a1.AIModelMap {
+	return &a1.AIModelMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "models", Namespace: "default"},
+		Spec:       a1.AIModelMapSpec{Vllm: variants},
+	}
+}
+
+var _ = Describe("AIDeploymentValidator", func() {
+	modelMap := newModelMap(a1.AIModelVariant{
+		Variant:     "opt",
+		AIModelSpec: a1.AIModelSpec{Uri: "facebook/opt-125m"},
+	})
+
+	newDeployment := func(engine a1.AIEngineName, models ...a1.AIModel) *a1.AIDeployment {
+		return &a1.AIDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "llm", Namespace: "default"},
+			Spec: a1.AIDeploymentSpec{
+				Engine: a1.AIEngine{Name: engine},
+				Models: models,
+			},
+		}
+	}
+
+	uri := func(u string) a1.AIModel {
+		return a1.AIModel{AIModelSpec: a1.AIModelSpec{Uri: u}}
+	}
+
+	ref := func(variant string) a1.AIModel {
+		return a1.AIModel{ModelMapRef: &a1.AIModelMapReference{Name: modelMap.Name, Variant: variant}}
+	}
+
+	DescribeTable("validate",
+		func(ai *a1.AIDeployment, invalid string, warning string) {
+			v := &webhooks.AIDeploymentValidator{Client: newClient(modelMap.DeepCopy())}
+
+			warnings, err := v.ValidateCreate(context.Background(), ai)
+			if invalid == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(apierrors.IsInvalid(err)).To(BeTrue(), "expected an Invalid error, got %v", err)
+				Expect(err.Error()).To(ContainSubstring(invalid))
+			}
+
+			if warning == "" {
+				Expect(warnings).To(BeEmpty())
+			} else {
+				Expect(warnings).To(ContainElement(ContainSubstring(warning)))
+			}
+		},
+		Entry("a single model",
+			newDeployment(a1.AIEngineNameVLLM, uri("facebook/opt-125m")), "", ""),
+		Entry("a variant of an AIModelMap",
+			newDeployment(a1.AIEngineNameVLLM, ref("opt")), "", ""),
+		Entry("multiple models on a single model engine",
+			newDeployment(a1.AIEngineNameTgi, uri("facebook/opt-125m"), uri("facebook/opt-350m")),
+			"only one model can be specified", ""),
+		Entry("no models on a single model engine",
+			newDeployment(a1.AIEngineNameTgi), "models not specified", ""),
+		Entry("an unknown engine",
+			newDeployment("no-such-engine", uri("facebook/opt-125m")), "spec.engine.name", ""),
+		Entry("a missing variant",
+			newDeployment(a1.AIEngineNameVLLM, ref("missing")), "no model variant missing", ""),
+		Entry("a missing AIModelMap is only a warning",
+			func() *a1.AIDeployment {
+				ai := newDeployment(a1.AIEngineNameVLLM, ref("opt"))
+				ai.Spec.Models[0].ModelMapRef.Name = "missing"
+				return ai
+			}(), "", "spec.models[0].modelMapRef"),
+		Entry("a generic engine without a pod template",
+			newDeployment(a1.AIEngineNameGeneric), "requires a pod template", ""),
+		Entry("a GPU requested without an accelerator",
+			func() *a1.AIDeployment {
+				ai := newDeployment(a1.AIEngineNameVLLM, uri("facebook/opt-125m"))
+				ai.Spec.Deployment.Resources.Requests = corev1.ResourceList{
+					constants.NvidiaGPULabel: resource.MustParse("1"),
+				}
+				return ai
+			}(), "no accelerator is specified", ""),
+		Entry("a bad inline LocalAI config",
+			newDeployment(a1.AIEngineNameLocalai, a1.AIModel{AIModelSpec: a1.AIModelSpec{
+				Uri:              "https://example.com/model.gguf",
+				EngineConfigFile: "- not a mapping",
+			}}), "spec.models[0].engineConfigFile", ""),
+		Entry("a missing ModelCache is only a warning",
+			func() *a1.AIDeployment {
+				ai := newDeployment(a1.AIEngineNameVLLM, uri("s3://models/opt-125m/"))
+				ai.Spec.ModelCacheRef = &corev1.LocalObjectReference{Name: "missing"}
+				return ai
+			}(), "", "ModelCache default/missing not found"),
+		Entry("a ModelCache on an engine which can't use one",
+			func() *a1.AIDeployment {
+				ai := newDeployment(a1.AIEngineNameOllama, uri("llama3"))
+				ai.Spec.ModelCacheRef = &corev1.LocalObjectReference{Name: "missing"}
+				return ai
+			}(), "can't load models from a ModelCache, only llamacpp, localai, sglang, tgi, triton, vllm can", "ModelCache default/missing not found"),
+	)
+
+	It("accepts an AIEngineTemplate as the engine", func() {
+		tmpl := &a1.AIEngineTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "custom"},
+			Spec:       a1.AIEngineTemplateSpec{Image: "example.com/engine:latest", MaxModels: 1},
+		}
+		v := &webhooks.AIDeploymentValidator{Client: newClient(tmpl)}
+
+		_, err := v.ValidateCreate(context.Background(), newDeployment("custom", uri("model")))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = v.ValidateCreate(context.Background(), newDeployment("custom", uri("model"), uri("other")))
+		Expect(err).To(MatchError(ContainSubstring("at most 1 models")))
+	})
+})
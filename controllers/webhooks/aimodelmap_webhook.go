@@ -0,0 +1,214 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/aimodelmap"
+	"github.com/premAI-io/prem-operator/controllers/engines"
+	"github.com/premAI-io/prem-operator/pkg/downloader"
+)
+
+//+kubebuilder:webhook:path=/validate-premlabs-io-v1alpha1-aimodelmap,mutating=false,failurePolicy=fail,sideEffects=None,groups=premlabs.io,resources=aimodelmaps,verbs=create;update,versions=v1alpha1,name=vaimodelmap.premlabs.io,admissionReviewVersions=v1
+
+// AIModelMapValidator rejects AIModelMaps with variants the engines can't
+// use. On update it warns about removed variants which are still referenced
+// by AIDeployments.
+type AIModelMapValidator struct {
+	Client client.Client
+}
+
+var _ admission.CustomValidator = &AIModelMapValidator{}
+
+// SetupWebhookWithManager registers the validator with the manager's webhook server
+func (v *AIModelMapValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&a1.AIModelMap{}).
+		WithValidator(v).
+		Complete()
+}
+
+func (v *AIModelMapValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	mm, ok := obj.(*a1.AIModelMap)
+	if !ok {
+		return nil, fmt.Errorf("expected an AIModelMap but got a %T", obj)
+	}
+
+	return nil, invalid("AIModelMap", mm.Name, validateModelMap(mm))
+}
+
+func (v *AIModelMapValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldMm, ok := oldObj.(*a1.AIModelMap)
+	if !ok {
+		return nil, fmt.Errorf("expected an AIModelMap but got a %T", oldObj)
+	}
+	mm, ok := newObj.(*a1.AIModelMap)
+	if !ok {
+		return nil, fmt.Errorf("expected an AIModelMap but got a %T", newObj)
+	}
+
+	if errs := validateModelMap(mm); len(errs) > 0 {
+		return nil, invalid("AIModelMap", mm.Name, errs)
+	}
+
+	warnings, err := v.removedVariantWarnings(ctx, oldMm, mm)
+	if err != nil {
+		return nil, err
+	}
+
+	return warnings, nil
+}
+
+func (v *AIModelMapValidator) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// variantsPath is the field path of the variants stored under key
+func variantsPath(key string) *field.Path {
+	if a1.IsAIModelMapOwnKey(key) {
+		return field.NewPath("spec").Child(key)
+	}
+
+	return field.NewPath("spec", "engines").Key(key)
+}
+
+func validateModelMap(mm *a1.AIModelMap) field.ErrorList {
+	var errs field.ErrorList
+
+	for key := range mm.Spec.Engines {
+		if a1.IsAIModelMapOwnKey(key) {
+			errs = append(errs, field.Invalid(field.NewPath("spec", "engines").Key(key), key,
+				fmt.Sprintf("use %s instead", variantsPath(key))))
+		}
+	}
+
+	defs := engines.Definitions()
+	for _, key := range mm.Spec.VariantKeys() {
+		path := variantsPath(key)
+		seen := map[string]bool{}
+
+		for i, v := range mm.Spec.Variants(key) {
+			vpath := path.Index(i)
+
+			if seen[v.Variant] {
+				errs = append(errs, field.Duplicate(vpath.Child("variant"), v.Variant))
+			}
+			seen[v.Variant] = true
+
+			// A variant can be described entirely by its engine config
+			// e.g. a Triton ensemble
+			if v.Uri != "" || v.EngineConfigFile == "" {
+				errs = append(errs, validateUri(vpath.Child("uri"), v.Uri)...)
+			}
+
+			if v.EngineConfigFile == "" {
+				continue
+			}
+
+			for _, def := range defs {
+				if def.ModelMapKey != key || def.ValidateEngineConfig == nil {
+					continue
+				}
+
+				if err := def.ValidateEngineConfig(v.EngineConfigFile); err != nil {
+					errs = append(errs, invalidField(vpath.Child("engineConfigFile"), err))
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+func validateUri(path *field.Path, uri string) field.ErrorList {
+	if strings.TrimSpace(uri) == "" {
+		return field.ErrorList{field.Required(path, "")}
+	}
+
+	if strings.TrimSpace(uri) != uri {
+		return field.ErrorList{field.Invalid(path, uri, "must not have leading or trailing whitespace")}
+	}
+
+	// A URI without a scheme is a model name or repository which the engine
+	// looks up itself, otherwise the downloader has to support it
+	scheme, _, found := strings.Cut(uri, "://")
+	if found && !slices.Contains(downloader.Schemes, scheme) {
+		return field.ErrorList{field.NotSupported(path.Key("scheme"), scheme, downloader.Schemes)}
+	}
+
+	return nil
+}
+
+// removedVariantWarnings finds AIDeployments which reference a variant that
+// is in oldMm but not in mm. These will fail when they are next reconciled.
+func (v *AIModelMapValidator) removedVariantWarnings(ctx context.Context, oldMm, mm *a1.AIModelMap) (admission.Warnings, error) {
+	removed := map[string]map[string]bool{}
+
+	for _, key := range oldMm.Spec.VariantKeys() {
+		for _, ov := range oldMm.Spec.Variants(key) {
+			found := false
+			for _, nv := range mm.Spec.Variants(key) {
+				if nv.Variant == ov.Variant {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				if removed[key] == nil {
+					removed[key] = map[string]bool{}
+				}
+				removed[key][ov.Variant] = true
+			}
+		}
+	}
+
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	deployments := &a1.AIDeploymentList{}
+	if err := v.Client.List(ctx, deployments, client.MatchingFields{
+		aimodelmap.ModelMapRefIndexKey: aimodelmap.RefIndexValue(mm.Namespace, mm.Name),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list AIDeployments referencing AIModelMap: %w", err)
+	}
+
+	var warnings admission.Warnings
+	for _, d := range deployments.Items {
+		def, err := engines.Get(ctx, v.Client, &d)
+		if err != nil {
+			continue
+		}
+
+		for _, m := range d.Spec.Models {
+			if m.ModelMapRef == nil || m.ModelMapRef.Name != mm.Name {
+				continue
+			}
+
+			namespace := m.ModelMapRef.Namespace
+			if namespace == "" {
+				namespace = d.Namespace
+			}
+			if namespace != mm.Namespace || !removed[def.ModelMapKey][m.ModelMapRef.Variant] {
+				continue
+			}
+
+			warnings = append(warnings, fmt.Sprintf(
+				"variant %s for %s was removed, but is used by AIDeployment %s/%s",
+				m.ModelMapRef.Variant, d.Spec.Engine.Name, d.Namespace, d.Name,
+			))
+		}
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,121 @@
+package webhooks_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	a1 "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/webhooks"
+)
+
+var _ = Describe("AIModelMapValidator", func() {
+	variant := func(name, uri string) a1.AIModelVariant {
+		return a1.AIModelVariant{Variant: name, AIModelSpec: a1.AIModelSpec{Uri: uri}}
+	}
+
+	DescribeTable("validate",
+		func(spec a1.AIModelMapSpec, invalid string) {
+			mm := &a1.AIModelMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "models", Namespace: "default"},
+				Spec:       spec,
+			}
+			v := &webhooks.AIModelMapValidator{Client: newClient()}
+
+			_, err := v.ValidateCreate(context.Background(), mm)
+			if invalid == "" {
+				Expect(err).NotTo(HaveOccurred())
+				return
+			}
+
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "expected an Invalid error, got %v", err)
+			Expect(err.Error()).To(ContainSubstring(invalid))
+		},
+		Entry("a model name",
+			a1.AIModelMapSpec{Vllm: []a1.AIModelVariant{variant("opt", "facebook/opt-125m")}}, ""),
+		Entry("an http URI",
+			a1.AIModelMapSpec{Vllm: []a1.AIModelVariant{variant("opt", "http://models.example.com/opt-125m.tar")}}, ""),
+		Entry("an https URI",
+			a1.AIModelMapSpec{Vllm: []a1.AIModelVariant{variant("opt", "https://models.example.com/opt-125m.tar")}}, ""),
+		Entry("an s3 URI",
+			a1.AIModelMapSpec{Vllm: []a1.AIModelVariant{variant("opt", "s3://models/opt-125m/")}}, ""),
+		Entry("a gs URI",
+			a1.AIModelMapSpec{Vllm: []a1.AIModelVariant{variant("opt", "gs://models/opt-125m/")}}, ""),
+		Entry("an hf URI",
+			a1.AIModelMapSpec{Vllm: []a1.AIModelVariant{variant("opt", "hf://facebook/opt-125m@main")}}, ""),
+		Entry("an oci URI",
+			a1.AIModelMapSpec{Vllm: []a1.AIModelVariant{variant("opt", "oci://registry.example.com/models/opt-125m:v1")}}, ""),
+		Entry("a duplicate variant",
+			a1.AIModelMapSpec{Vllm: []a1.AIModelVariant{
+				variant("opt", "facebook/opt-125m"),
+				variant("opt", "facebook/opt-350m"),
+			}}, "spec.vllm[1].variant: Duplicate value"),
+		Entry("a missing URI",
+			a1.AIModelMapSpec{Vllm: []a1.AIModelVariant{variant("opt", "")}}, "spec.vllm[0].uri: Required value"),
+		Entry("a URI with whitespace",
+			a1.AIModelMapSpec{Vllm: []a1.AIModelVariant{variant("opt", " facebook/opt-125m")}}, "leading or trailing whitespace"),
+		Entry("an unsupported URI scheme",
+			a1.AIModelMapSpec{Vllm: []a1.AIModelVariant{variant("opt", "ftp://example.com/opt")}}, "Unsupported value: \"ftp\""),
+		Entry("an engine with its own field under engines",
+			a1.AIModelMapSpec{Engines: map[string][]a1.AIModelVariant{
+				a1.AIModelMapKeyVllm: {variant("opt", "facebook/opt-125m")},
+			}}, "use spec.vllm instead"),
+		Entry("bad LocalAI config YAML",
+			a1.AIModelMapSpec{Localai: []a1.AIModelVariant{{
+				Variant:     "phi",
+				AIModelSpec: a1.AIModelSpec{Uri: "https://example.com/phi.gguf", EngineConfigFile: "name: [phi"},
+			}}}, "spec.localai[0].engineConfigFile"),
+		Entry("a LocalAI config without a name",
+			a1.AIModelMapSpec{Localai: []a1.AIModelVariant{{
+				Variant:     "phi",
+				AIModelSpec: a1.AIModelSpec{Uri: "https://example.com/phi.gguf", EngineConfigFile: "backend: llama"},
+			}}}, "must have a name"),
+		Entry("a Modelfile without FROM",
+			a1.AIModelMapSpec{Ollama: []a1.AIModelVariant{{
+				Variant:     "pirate",
+				AIModelSpec: a1.AIModelSpec{Uri: "llama3", EngineConfigFile: "SYSTEM You are a pirate"},
+			}}}, "must have a FROM instruction"),
+		Entry("a Modelfile",
+			a1.AIModelMapSpec{Ollama: []a1.AIModelVariant{{
+				Variant:     "pirate",
+				AIModelSpec: a1.AIModelSpec{Uri: "llama3", EngineConfigFile: "FROM llama3\nSYSTEM You are a pirate"},
+			}}}, ""),
+	)
+
+	Describe("update", func() {
+		oldMm := newModelMap(variant("opt", "facebook/opt-125m"), variant("phi", "microsoft/phi-2"))
+
+		user := func(variant string) *a1.AIDeployment {
+			return &a1.AIDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "uses-" + variant, Namespace: "default"},
+				Spec: a1.AIDeploymentSpec{
+					Engine: a1.AIEngine{Name: a1.AIEngineNameVLLM},
+					Models: []a1.AIModel{{
+						ModelMapRef: &a1.AIModelMapReference{Name: oldMm.Name, Variant: variant},
+					}},
+				},
+			}
+		}
+
+		It("warns when a variant an AIDeployment uses is removed", func() {
+			v := &webhooks.AIModelMapValidator{Client: newClient(user("opt"), user("phi"))}
+
+			mm := newModelMap(variant("phi", "microsoft/phi-2"))
+			warnings, err := v.ValidateUpdate(context.Background(), oldMm, mm)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ConsistOf(ContainSubstring("variant opt for vllm was removed, but is used by AIDeployment default/uses-opt")))
+		})
+
+		It("doesn't warn about unused variants", func() {
+			v := &webhooks.AIModelMapValidator{Client: newClient(user("phi"))}
+
+			mm := newModelMap(variant("phi", "microsoft/phi-2"))
+			warnings, err := v.ValidateUpdate(context.Background(), oldMm, mm)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+		})
+	})
+})
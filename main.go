@@ -24,10 +24,14 @@ import (
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -35,6 +39,9 @@ import (
 	"github.com/premAI-io/prem-operator/api/v1alpha1"
 	premlabsv1alpha1 "github.com/premAI-io/prem-operator/api/v1alpha1"
 	"github.com/premAI-io/prem-operator/controllers"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/engines"
+	"github.com/premAI-io/prem-operator/controllers/webhooks"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -74,8 +81,17 @@ func main() {
 			BindAddress: metricsAddr,
 		},
 		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "ca51f953.premlabs.io",
+		// Only the pods which download models are read, so the others in the
+		// cluster aren't cached
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.Pod{}: {
+					Label: labels.SelectorFromSet(labels.Set{constants.ModelDownloadLabel: "true"}),
+				},
+			},
+		},
+		LeaderElection:   enableLeaderElection,
+		LeaderElectionID: "ca51f953.premlabs.io",
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -93,9 +109,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	// The downloader is shipped in the operator's image, which it can't find
+	// out for itself
+	if image := os.Getenv("DOWNLOADER_IMAGE"); image != "" {
+		engines.DownloaderImage = image
+	}
+
 	if err = (&controllers.AIDeploymentReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		APIReader: mgr.GetAPIReader(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AIDeployment")
 		os.Exit(1)
@@ -117,6 +140,39 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "AIModelMap")
 		os.Exit(1)
 	}
+
+	if err = (&controllers.ModelCacheReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ModelCache")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.AIModelPrefetchReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AIModelPrefetch")
+		os.Exit(1)
+	}
+
+	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
+		if err = (&webhooks.AIDeploymentValidator{
+			Client:    mgr.GetClient(),
+			APIReader: mgr.GetAPIReader(),
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AIDeployment")
+			os.Exit(1)
+		}
+
+		if err = (&webhooks.AIModelMapValidator{
+			Client: mgr.GetClient(),
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AIModelMap")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,132 @@
+package downloader
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// bucketSource is an object, or the objects under a prefix, in an S3
+// compatible bucket. GCS is accessed through its XML API, which is
+// compatible for the requests made here.
+type bucketSource struct {
+	client *http.Client
+	key    string
+	// request creates an authenticated GET request for the object at key,
+	// or the bucket if key is empty
+	request func(ctx context.Context, key string, query url.Values) (*http.Request, error)
+}
+
+// listBucketResult is the response of ListObjects
+type listBucketResult struct {
+	IsTruncated bool
+	NextMarker  string
+	Contents    []struct {
+		Key string
+	}
+}
+
+func (s *bucketSource) files(ctx context.Context) ([]file, error) {
+	if s.key != "" && !strings.HasSuffix(s.key, "/") {
+		return []file{s.file(s.key, "")}, nil
+	}
+
+	files := []file{}
+	marker := ""
+	for {
+		query := url.Values{"prefix": {s.key}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+
+		req, err := s.request(ctx, "", query)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := do(s.client, req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range result.Contents {
+			// Folders created by consoles are empty objects ending in a slash
+			if strings.HasSuffix(c.Key, "/") {
+				continue
+			}
+			files = append(files, s.file(c.Key, strings.TrimPrefix(c.Key, s.key)))
+			marker = c.Key
+		}
+		if result.NextMarker != "" {
+			marker = result.NextMarker
+		}
+
+		if !result.IsTruncated || len(result.Contents) == 0 {
+			return files, nil
+		}
+	}
+}
+
+func (s *bucketSource) file(key, path string) file {
+	return file{
+		path: path,
+		request: func(ctx context.Context) (*http.Request, error) {
+			return s.request(ctx, key, nil)
+		},
+	}
+}
+
+// escapePath escapes each segment of a key with uriEscape
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = uriEscape(s)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// uriEscape escapes everything except the unreserved characters of RFC 3986,
+// as AWS signatures require
+func uriEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+
+	return b.String()
+}
+
+// encodeQuery encodes query sorted by key with uriEscape
+func encodeQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := []string{}
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, uriEscape(k)+"="+uriEscape(v))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
@@ -0,0 +1,559 @@
+// Package downloader fetches the model files of an AIDeployment in the init
+// containers of engines. Models can be at an http(s) URL, in an S3 or GCS
+// bucket, on the Hugging Face Hub or in an OCI registry. A download is written
+// to a partial file which is resumed on the next attempt, failed attempts are
+// retried with backoff and the result can be checked against a SHA-256
+// digest.
+package downloader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Exit codes of the downloader command for each kind of failure
+const (
+	ExitUsage    = 2
+	ExitDownload = 3
+	ExitChecksum = 4
+	ExitWrite    = 5
+)
+
+const (
+	DefaultTries   = 5
+	DefaultBackoff = 2 * time.Second
+
+	partSuffix  = ".part"
+	archiveName = ".archive"
+	// completeName is the file written into the output directory once every
+	// file of a directory is downloaded, or an archive is extracted, it holds
+	// the archive's sha256
+	completeName = ".complete"
+	maxBackoff   = time.Minute
+)
+
+// Error is returned by Download, Code is the exit code for the failure
+type Error struct {
+	Code int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code for err, 0 if it is nil
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	return 1
+}
+
+// ErrChecksum is returned when the file doesn't match Options.SHA256
+var ErrChecksum = errors.New("checksum mismatch")
+
+// statusError is a response which isn't a success, those which won't change
+// by retrying are permanent
+type statusError struct {
+	status int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("server responded with %d %s", e.status, http.StatusText(e.status))
+}
+
+func (e *statusError) permanent() bool {
+	return e.status >= 400 && e.status < 500 &&
+		e.status != http.StatusRequestTimeout && e.status != http.StatusTooManyRequests
+}
+
+type Options struct {
+	// URL to download, see Supported for the schemes
+	URL string
+	// Output is the file to write, or the directory to unpack into if
+	// Extract is set. It is always a directory if the URL is, see
+	// IsDirectory.
+	Output string
+	// SHA256 is the hex encoded digest of the file, or of the archive if
+	// Extract is set. It isn't checked if empty and can't be set for a
+	// directory.
+	SHA256 string
+	// Extract unpacks the file as a tar archive, which may be gzipped. The
+	// archive isn't downloaded again once it has been extracted, unless
+	// SHA256 is set and doesn't match it.
+	Extract bool
+	// From is a directory which may already have the download, such as
+	// one prefetched onto the node. If the download in it is finished then
+	// Output is made a symlink to it. It can only be set if Output is a
+	// directory.
+	From string
+	// Tries is how many times the download is attempted
+	Tries int
+	// Backoff is the wait after the first failed attempt, it is doubled
+	// after each attempt up to a minute
+	Backoff time.Duration
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+}
+
+// file is one of the files of a source
+type file struct {
+	// path is relative to the output directory, it is empty for a source
+	// with a single file
+	path string
+	// sha256 is the file's digest if the source knows it
+	sha256 string
+	// extract unpacks the file, as a tar archive, into path
+	extract bool
+	// request creates the request for the file, it is called for each attempt
+	request func(ctx context.Context) (*http.Request, error)
+}
+
+// source is where a URI's files are fetched from, see newSource for the
+// supported schemes
+type source interface {
+	files(ctx context.Context) ([]file, error)
+}
+
+// Download fetches o.URL into o.Output. A file which already exists, or an
+// archive which was already extracted, and matches its checksum if there is
+// one, isn't downloaded again so an init container can be restarted cheaply.
+func Download(ctx context.Context, o Options) error {
+	src, err := o.validate()
+	if err != nil {
+		return &Error{Code: ExitUsage, Err: err}
+	}
+
+	if o.From != "" {
+		linked, err := o.link()
+		if err != nil || linked {
+			return err
+		}
+	}
+
+	if IsDirectory(o.URL) {
+		done, err := finished(o.Output, "")
+		if err != nil {
+			return err
+		}
+		if done {
+			log.Info("Already downloaded ", o.URL, " into ", o.Output)
+			return nil
+		}
+	}
+
+	var files []file
+	if err := o.retry(ctx, func() error {
+		files, err = src.files(ctx)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if !IsDirectory(o.URL) {
+		f := files[0]
+		f.sha256 = o.SHA256
+		f.extract = o.Extract
+		return o.download(ctx, f, o.Output)
+	}
+
+	log.Info("Downloading ", len(files), " files from ", o.URL, " into ", o.Output)
+	for _, f := range files {
+		target, err := within(o.Output, f.path)
+		if err != nil {
+			return &Error{Code: ExitDownload, Err: err}
+		}
+
+		if err := o.download(ctx, f, target); err != nil {
+			return err
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(o.Output, completeName), nil, 0o644); err != nil {
+		return &Error{Code: ExitWrite, Err: err}
+	}
+
+	return nil
+}
+
+// link makes o.Output a symlink to o.From if the download in From is
+// finished. It is false if it isn't, or if something else is at Output, in
+// which case the URL is downloaded as usual.
+func (o *Options) link() (bool, error) {
+	done, err := finished(o.From, o.SHA256)
+	if err != nil || !done {
+		return false, err
+	}
+
+	if target, err := os.Readlink(o.Output); err == nil && target == o.From {
+		return true, nil
+	}
+	if _, err := os.Lstat(o.Output); err == nil {
+		return false, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, &Error{Code: ExitWrite, Err: err}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(o.Output), 0o755); err != nil {
+		return false, &Error{Code: ExitWrite, Err: err}
+	}
+	if err := os.Symlink(o.From, o.Output); err != nil {
+		return false, &Error{Code: ExitWrite, Err: err}
+	}
+
+	log.Info("Using the download in ", o.From, " for ", o.Output)
+	return true, nil
+}
+
+// download fetches f to target, or unpacks it into target if f.extract is set
+func (o *Options) download(ctx context.Context, f file, target string) error {
+	name := target
+	marker := filepath.Join(target, completeName)
+	if f.extract {
+		if err := os.MkdirAll(target, 0o755); err != nil {
+			return &Error{Code: ExitWrite, Err: err}
+		}
+		name = filepath.Join(target, archiveName)
+
+		done, err := finished(target, f.sha256)
+		if err != nil {
+			return err
+		}
+		if done {
+			log.Info("Already extracted into ", target)
+			return nil
+		}
+
+		// The marker of a different archive is removed, so it isn't left
+		// behind if extracting the new archive fails
+		if err := os.Remove(marker); err == nil {
+			log.Warn(target, " was extracted from a different archive, downloading it again")
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return &Error{Code: ExitWrite, Err: err}
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return &Error{Code: ExitWrite, Err: err}
+		}
+
+		done, err := complete(name, f.sha256)
+		if err != nil {
+			return err
+		}
+		if done {
+			log.Info("Already downloaded ", target)
+			return nil
+		}
+	}
+
+	part := name + partSuffix
+	if err := o.retry(ctx, func() error {
+		if err := o.fetch(ctx, f, part); err != nil {
+			return err
+		}
+		return check(part, f.sha256)
+	}); err != nil {
+		return err
+	}
+
+	if f.extract {
+		sum, err := digest(part)
+		if err != nil {
+			return &Error{Code: ExitWrite, Err: err}
+		}
+
+		log.Info("Extracting into ", target)
+		if err := extract(part, target); err != nil {
+			return &Error{Code: ExitWrite, Err: fmt.Errorf("extracting %s: %w", o.URL, err)}
+		}
+		if err := os.WriteFile(marker, []byte(sum+"\n"), 0o644); err != nil {
+			return &Error{Code: ExitWrite, Err: err}
+		}
+		if err := os.Remove(part); err != nil {
+			return &Error{Code: ExitWrite, Err: err}
+		}
+		return nil
+	}
+
+	if err := os.Rename(part, name); err != nil {
+		return &Error{Code: ExitWrite, Err: err}
+	}
+
+	log.Info("Downloaded ", target)
+	return nil
+}
+
+// Size is the number of bytes in the regular files at path, which may be a
+// file or a directory, not counting the marker of a finished download
+func Size(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() || d.Name() == completeName {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+
+		return nil
+	})
+
+	return size, err
+}
+
+func (o *Options) validate() (source, error) {
+	u, err := url.Parse(o.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	if o.Output == "" {
+		return nil, fmt.Errorf("no output given")
+	}
+
+	if o.SHA256 != "" {
+		if b, err := hex.DecodeString(o.SHA256); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("sha256 must be %d hex characters", sha256.Size*2)
+		}
+		if IsDirectory(o.URL) {
+			return nil, fmt.Errorf("sha256 can only be checked for a single file, but %s is several", o.URL)
+		}
+	}
+
+	if o.From != "" && !o.Extract && !IsDirectory(o.URL) {
+		return nil, fmt.Errorf("from can only be given when the output is a directory")
+	}
+
+	if o.Tries < 1 {
+		o.Tries = 1
+	}
+	if o.Client == nil {
+		o.Client = http.DefaultClient
+	}
+
+	return newSource(u, o.Client)
+}
+
+// complete is true if file exists and matches the checksum. A file which
+// doesn't match is removed.
+func complete(file, sum string) (bool, error) {
+	if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, &Error{Code: ExitWrite, Err: err}
+	}
+
+	if sum == "" {
+		return true, nil
+	}
+
+	if err := verify(file, sum); err == nil {
+		return true, nil
+	} else if !errors.Is(err, ErrChecksum) {
+		return false, &Error{Code: ExitWrite, Err: err}
+	}
+
+	log.Warn(file, " doesn't match its checksum, downloading it again")
+	if err := os.Remove(file); err != nil {
+		return false, &Error{Code: ExitWrite, Err: err}
+	}
+
+	return false, nil
+}
+
+// finished is true if dir has the marker of a finished download, which holds
+// the checksum unless sum is empty
+func finished(dir, sum string) (bool, error) {
+	b, err := os.ReadFile(filepath.Join(dir, completeName))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, &Error{Code: ExitWrite, Err: err}
+	}
+
+	return sum == "" || strings.EqualFold(strings.TrimSpace(string(b)), sum), nil
+}
+
+// retry calls fn until it succeeds, the error is permanent or o.Tries is
+// reached
+func (o *Options) retry(ctx context.Context, fn func() error) error {
+	backoff := o.Backoff
+	var err error
+
+	for try := 1; try <= o.Tries; try++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+
+		var status *statusError
+		if errors.As(err, &status) && status.permanent() {
+			break
+		}
+		if ctx.Err() != nil || try == o.Tries {
+			break
+		}
+
+		log.Warnf("Attempt %d of %d to download %s failed, retrying in %s: %v", try, o.Tries, o.URL, backoff, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+
+	code := ExitDownload
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	if errors.Is(err, ErrChecksum) {
+		code = ExitChecksum
+	}
+
+	return &Error{Code: code, Err: fmt.Errorf("downloading %s: %w", o.URL, err)}
+}
+
+// fetch appends the rest of the file to part, starting from the beginning if
+// the server doesn't support ranges
+func (o *Options) fetch(ctx context.Context, f file, part string) error {
+	out, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return &Error{Code: ExitWrite, Err: err}
+	}
+	defer out.Close()
+
+	offset, err := out.Seek(0, io.SeekEnd)
+	if err != nil {
+		return &Error{Code: ExitWrite, Err: err}
+	}
+
+	req, err := f.request(ctx)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		log.Info("Resuming ", req.URL.Redacted(), " from ", offset, " bytes")
+	case http.StatusOK:
+		if err := out.Truncate(0); err != nil {
+			return &Error{Code: ExitWrite, Err: err}
+		}
+		if _, err := out.Seek(0, io.SeekStart); err != nil {
+			return &Error{Code: ExitWrite, Err: err}
+		}
+		log.Info("Downloading ", req.URL.Redacted())
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is already the whole file
+		if offset > 0 {
+			return nil
+		}
+		return &statusError{status: resp.StatusCode}
+	default:
+		return &statusError{status: resp.StatusCode}
+	}
+
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// check verifies part if there is a checksum, a part which doesn't match is
+// removed so the next attempt starts again
+func check(part, sum string) error {
+	if sum == "" {
+		return nil
+	}
+
+	err := verify(part, sum)
+	if errors.Is(err, ErrChecksum) {
+		if err := os.Remove(part); err != nil {
+			return &Error{Code: ExitWrite, Err: err}
+		}
+	}
+
+	return err
+}
+
+func verify(file, sum string) error {
+	got, err := digest(file)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(got, sum) {
+		return fmt.Errorf("%w: expected sha256 %s, got %s", ErrChecksum, sum, got)
+	}
+
+	return nil
+}
+
+// digest is the hex encoded sha256 of file
+func digest(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// do sends a request which isn't for a file, such as a listing, and returns
+// the response if it succeeded
+func do(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &statusError{status: resp.StatusCode}
+	}
+
+	return resp, nil
+}
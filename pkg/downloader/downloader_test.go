@@ -0,0 +1,244 @@
+package downloader_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/premAI-io/prem-operator/pkg/downloader"
+)
+
+var _ = Describe("Download", func() {
+	var (
+		content  = bytes.Repeat([]byte("prem-operator"), 1000)
+		dir      string
+		requests atomic.Int32
+		failures int32
+		server   *httptest.Server
+	)
+
+	sum := func(b []byte) string {
+		s := sha256.Sum256(b)
+		return hex.EncodeToString(s[:])
+	}
+
+	serve := func(body []byte) {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) <= failures {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			http.ServeContent(w, r, "model", time.Time{}, bytes.NewReader(body))
+		}))
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		requests.Store(0)
+		failures = 0
+		serve(content)
+		DeferCleanup(func() { server.Close() })
+	})
+
+	options := func(name string) downloader.Options {
+		return downloader.Options{
+			URL:     server.URL + "/" + name,
+			Output:  filepath.Join(dir, name),
+			Tries:   3,
+			Backoff: time.Millisecond,
+		}
+	}
+
+	It("downloads and verifies a file", func() {
+		o := options("model.gguf")
+		o.SHA256 = sum(content)
+
+		Expect(downloader.Download(context.Background(), o)).To(Succeed())
+		Expect(os.ReadFile(o.Output)).To(Equal(content))
+
+		By("skipping a file which is already downloaded")
+		Expect(downloader.Download(context.Background(), o)).To(Succeed())
+		Expect(requests.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("exits with the checksum code on a mismatch", func() {
+		o := options("model.gguf")
+		o.SHA256 = sum([]byte("something else"))
+
+		err := downloader.Download(context.Background(), o)
+		Expect(downloader.ExitCode(err)).To(Equal(downloader.ExitChecksum))
+		Expect(o.Output).NotTo(BeAnExistingFile())
+	})
+
+	It("resumes a partial file", func() {
+		o := options("model.gguf")
+		o.SHA256 = sum(content)
+		Expect(os.WriteFile(o.Output+".part", content[:500], 0o644)).To(Succeed())
+
+		Expect(downloader.Download(context.Background(), o)).To(Succeed())
+		Expect(os.ReadFile(o.Output)).To(Equal(content))
+	})
+
+	It("retries server errors", func() {
+		failures = 2
+		o := options("model.gguf")
+
+		Expect(downloader.Download(context.Background(), o)).To(Succeed())
+		Expect(requests.Load()).To(BeEquivalentTo(3))
+
+		By("giving up after the last try")
+		requests.Store(0)
+		failures = 3
+		o = options("other.gguf")
+		err := downloader.Download(context.Background(), o)
+		Expect(downloader.ExitCode(err)).To(Equal(downloader.ExitDownload))
+	})
+
+	It("doesn't retry a missing file", func() {
+		server.Close()
+		server = httptest.NewServer(http.NotFoundHandler())
+
+		err := downloader.Download(context.Background(), options("model.gguf"))
+		Expect(downloader.ExitCode(err)).To(Equal(downloader.ExitDownload))
+		Expect(err).To(MatchError(ContainSubstring("404")))
+	})
+
+	It("rejects an invalid checksum", func() {
+		o := options("model.gguf")
+		o.SHA256 = "abc"
+
+		err := downloader.Download(context.Background(), o)
+		Expect(downloader.ExitCode(err)).To(Equal(downloader.ExitUsage))
+	})
+
+	tarball := func(weights []byte) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		Expect(tw.WriteHeader(&tar.Header{Name: "adapter/", Typeflag: tar.TypeDir, Mode: 0o755})).To(Succeed())
+		Expect(tw.WriteHeader(&tar.Header{Name: "adapter/weights.bin", Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(weights))})).To(Succeed())
+		_, err := tw.Write(weights)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tw.Close()).To(Succeed())
+		Expect(gz.Close()).To(Succeed())
+
+		return buf.Bytes()
+	}
+
+	It("extracts a gzipped tar", func() {
+		archive := tarball(content)
+		server.Close()
+		serve(archive)
+
+		o := options("adapter.tar.gz")
+		o.Output = dir
+		o.Extract = true
+		Expect(downloader.Download(context.Background(), o)).To(Succeed())
+		Expect(os.ReadFile(filepath.Join(dir, "adapter", "weights.bin"))).To(Equal(content))
+		Expect(filepath.Join(dir, ".archive")).NotTo(BeAnExistingFile())
+		Expect(os.ReadFile(filepath.Join(dir, ".complete"))).To(BeEquivalentTo(sum(archive) + "\n"))
+		Expect(downloader.Size(dir)).To(BeEquivalentTo(len(content)))
+
+		By("skipping an archive which is already extracted")
+		Expect(downloader.Download(context.Background(), o)).To(Succeed())
+		o.SHA256 = sum(archive)
+		Expect(downloader.Download(context.Background(), o)).To(Succeed())
+		Expect(requests.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("extracts an archive again when its checksum changes", func() {
+		server.Close()
+		serve(tarball(content))
+
+		o := options("adapter.tar.gz")
+		o.Output = dir
+		o.Extract = true
+		Expect(downloader.Download(context.Background(), o)).To(Succeed())
+
+		changed := tarball([]byte("new weights"))
+		server.Close()
+		serve(changed)
+		o.URL = server.URL + "/adapter.tar.gz"
+		o.SHA256 = sum(changed)
+		Expect(downloader.Download(context.Background(), o)).To(Succeed())
+		Expect(requests.Load()).To(BeEquivalentTo(2))
+		Expect(os.ReadFile(filepath.Join(dir, "adapter", "weights.bin"))).To(BeEquivalentTo("new weights"))
+		Expect(os.ReadFile(filepath.Join(dir, ".complete"))).To(BeEquivalentTo(sum(changed) + "\n"))
+	})
+
+	It("links to a finished download", func() {
+		archive := tarball(content)
+		server.Close()
+		serve(archive)
+
+		prefetched := filepath.Join(dir, "prefetched")
+		o := options("adapter.tar.gz")
+		o.Output = filepath.Join(dir, "downloads", "adapter")
+		o.Extract = true
+		o.SHA256 = sum(archive)
+		o.From = prefetched
+
+		By("downloading when the directory doesn't have it")
+		Expect(downloader.Download(context.Background(), o)).To(Succeed())
+		Expect(requests.Load()).To(BeEquivalentTo(1))
+		Expect(os.Readlink(o.Output)).Error().To(HaveOccurred())
+		Expect(os.RemoveAll(o.Output)).To(Succeed())
+
+		By("linking to the directory once it has it")
+		p := o
+		p.Output = prefetched
+		p.From = ""
+		Expect(downloader.Download(context.Background(), p)).To(Succeed())
+
+		Expect(downloader.Download(context.Background(), o)).To(Succeed())
+		Expect(requests.Load()).To(BeEquivalentTo(2))
+		Expect(os.Readlink(o.Output)).To(Equal(prefetched))
+		Expect(os.ReadFile(filepath.Join(o.Output, "adapter", "weights.bin"))).To(Equal(content))
+
+		By("downloading when the directory has a different archive")
+		Expect(os.Remove(o.Output)).To(Succeed())
+		o.SHA256 = sum(tarball([]byte("new weights")))
+		err := downloader.Download(context.Background(), o)
+		Expect(downloader.ExitCode(err)).To(Equal(downloader.ExitChecksum))
+		Expect(os.Readlink(o.Output)).Error().To(HaveOccurred())
+	})
+
+	It("rejects from for a single file", func() {
+		o := options("model.gguf")
+		o.From = dir
+
+		err := downloader.Download(context.Background(), o)
+		Expect(downloader.ExitCode(err)).To(Equal(downloader.ExitUsage))
+	})
+
+	It("rejects archive entries outside of the output", func() {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		Expect(tw.WriteHeader(&tar.Header{Name: "../escape", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1})).To(Succeed())
+		_, err := tw.Write([]byte("x"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tw.Close()).To(Succeed())
+
+		server.Close()
+		serve(buf.Bytes())
+
+		o := options("adapter.tar")
+		o.Output = filepath.Join(dir, "out")
+		o.Extract = true
+		err = downloader.Download(context.Background(), o)
+		Expect(downloader.ExitCode(err)).To(Equal(downloader.ExitWrite))
+		Expect(filepath.Join(dir, "escape")).NotTo(BeAnExistingFile())
+	})
+})
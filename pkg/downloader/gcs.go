@@ -0,0 +1,193 @@
+package downloader
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gcsEndpoint  = "https://storage.googleapis.com"
+	gcsScope     = "https://www.googleapis.com/auth/devstorage.read_only"
+	gcsTokenURI  = "https://oauth2.googleapis.com/token"
+	gcsTokenLife = time.Hour
+)
+
+// gcsServiceAccount is the part of a service account key that is needed to
+// get an access token
+type gcsServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// newGCSSource returns the source for gs://bucket/object. It is
+// authenticated with a service account key, which is read from the
+// GOOGLE_CREDENTIALS env var or the file in GOOGLE_APPLICATION_CREDENTIALS.
+// Requests are anonymous without one. STORAGE_EMULATOR_HOST replaces the
+// endpoint.
+func newGCSSource(u *url.URL, client *http.Client) (source, error) {
+	endpoint := gcsEndpoint
+	if e := os.Getenv("STORAGE_EMULATOR_HOST"); e != "" {
+		endpoint = strings.TrimSuffix(e, "/")
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "http://" + endpoint
+		}
+	}
+
+	key := []byte(os.Getenv("GOOGLE_CREDENTIALS"))
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); len(key) == 0 && path != "" {
+		var err error
+		if key, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("reading GOOGLE_APPLICATION_CREDENTIALS: %w", err)
+		}
+	}
+
+	var tokens *gcsTokenSource
+	if len(key) > 0 {
+		account := gcsServiceAccount{}
+		if err := json.Unmarshal(key, &account); err != nil {
+			return nil, fmt.Errorf("invalid service account key: %w", err)
+		}
+		if account.TokenURI == "" {
+			account.TokenURI = gcsTokenURI
+		}
+
+		block, _ := pem.Decode([]byte(account.PrivateKey))
+		if block == nil {
+			return nil, fmt.Errorf("service account key has no private key")
+		}
+		private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid service account private key: %w", err)
+		}
+		rsaKey, ok := private.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("service account private key isn't RSA")
+		}
+
+		tokens = &gcsTokenSource{client: client, account: account, key: rsaKey}
+	}
+
+	bucket := u.Host
+	return &bucketSource{
+		client: client,
+		key:    strings.TrimPrefix(u.Path, "/"),
+		request: func(ctx context.Context, key string, query url.Values) (*http.Request, error) {
+			target := endpoint + "/" + uriEscape(bucket) + "/" + escapePath(key)
+			if len(query) > 0 {
+				target += "?" + encodeQuery(query)
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			if tokens != nil {
+				token, err := tokens.token(ctx)
+				if err != nil {
+					return nil, err
+				}
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			return req, nil
+		},
+	}, nil
+}
+
+// gcsTokenSource exchanges a JWT signed with the service account's key for
+// an access token, which is reused until shortly before it expires
+type gcsTokenSource struct {
+	client  *http.Client
+	account gcsServiceAccount
+	key     *rsa.PrivateKey
+
+	mu      sync.Mutex
+	current string
+	expiry  time.Time
+}
+
+func (s *gcsTokenSource) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.current != "" && now.Add(time.Minute).Before(s.expiry) {
+		return s.current, nil
+	}
+
+	assertion, err := s.assertion(now)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := do(s.client, req)
+	if err != nil {
+		return "", fmt.Errorf("getting an access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("getting an access token: %w", err)
+	}
+
+	s.current = result.AccessToken
+	s.expiry = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return s.current, nil
+}
+
+// assertion creates the JWT which is exchanged for an access token
+func (s *gcsTokenSource) assertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iss":   s.account.ClientEmail,
+		"scope": gcsScope,
+		"aud":   s.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(gcsTokenLife).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + enc.EncodeToString(signature), nil
+}
@@ -0,0 +1,118 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	hfEndpoint        = "https://huggingface.co"
+	hfDefaultRevision = "main"
+)
+
+// hfSource is a model repository on the Hugging Face Hub, or a single file
+// of it. HF_TOKEN authenticates the requests, which is needed for private
+// and gated models, and HF_ENDPOINT replaces the Hub with a mirror.
+type hfSource struct {
+	client   *http.Client
+	endpoint string
+	token    string
+	repo     string
+	revision string
+	file     string
+}
+
+// parseHF splits hf://org/repo[@revision][/file] into its parts, the
+// revision defaults to main
+func parseHF(u *url.URL) (repo, revision, file string) {
+	name, file, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	name, revision, _ = strings.Cut(name, "@")
+	if revision == "" {
+		revision = hfDefaultRevision
+	}
+
+	return u.Host + "/" + name, revision, file
+}
+
+func newHFSource(u *url.URL, client *http.Client) *hfSource {
+	endpoint := hfEndpoint
+	if e := os.Getenv("HF_ENDPOINT"); e != "" {
+		endpoint = strings.TrimSuffix(e, "/")
+	}
+
+	repo, revision, file := parseHF(u)
+	return &hfSource{
+		client:   client,
+		endpoint: endpoint,
+		token:    os.Getenv("HF_TOKEN"),
+		repo:     repo,
+		revision: revision,
+		file:     file,
+	}
+}
+
+func (s *hfSource) files(ctx context.Context) ([]file, error) {
+	if s.file != "" {
+		return []file{s.resolve(s.revision, s.file, "")}, nil
+	}
+
+	req, err := s.request(ctx, fmt.Sprintf("%s/api/models/%s/revision/%s", s.endpoint, s.repo, url.PathEscape(s.revision)))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := do(s.client, req)
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", s.repo, err)
+	}
+	defer resp.Body.Close()
+
+	var info struct {
+		Sha      string `json:"sha"`
+		Siblings []struct {
+			Filename string `json:"rfilename"`
+		} `json:"siblings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("listing %s: %w", s.repo, err)
+	}
+
+	// All of the files come from the commit the revision was at when listed
+	revision := s.revision
+	if info.Sha != "" {
+		revision = info.Sha
+	}
+
+	files := make([]file, 0, len(info.Siblings))
+	for _, f := range info.Siblings {
+		files = append(files, s.resolve(revision, f.Filename, f.Filename))
+	}
+
+	return files, nil
+}
+
+func (s *hfSource) resolve(revision, name, path string) file {
+	return file{
+		path: path,
+		request: func(ctx context.Context) (*http.Request, error) {
+			return s.request(ctx, fmt.Sprintf("%s/%s/resolve/%s/%s", s.endpoint, s.repo, url.PathEscape(revision), escapePath(name)))
+		},
+	}
+}
+
+func (s *hfSource) request(ctx context.Context, target string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	return req, nil
+}
@@ -0,0 +1,300 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ociTitleAnnotation = "org.opencontainers.image.title"
+	// Set by ORAS on a layer which is a packed directory
+	ociUnpackAnnotation = "io.deis.oras.content.unpack"
+
+	ociManifestType       = "application/vnd.oci.image.manifest.v1+json"
+	dockerManifestType    = "application/vnd.docker.distribution.manifest.v2+json"
+	ociDefaultTag         = "latest"
+	ociDefaultTokenExpiry = time.Minute
+)
+
+// ociSource is an artifact in an OCI registry, such as one pushed by ORAS.
+// Each layer with a title annotation is a file of that name, unless it is a
+// directory which ORAS packed, and other tar layers are unpacked into the
+// output. OCI_USERNAME and OCI_PASSWORD authenticate with the registry.
+type ociSource struct {
+	client    *http.Client
+	registry  string
+	repo      string
+	reference string
+	username  string
+	password  string
+
+	mu        sync.Mutex
+	challenge *ociChallenge
+	auth      string
+	expiry    time.Time
+}
+
+// ociChallenge is how the registry asked to be authenticated
+type ociChallenge struct {
+	scheme string
+	params map[string]string
+}
+
+type ociManifest struct {
+	MediaType string `json:"mediaType"`
+	Layers    []struct {
+		MediaType   string            `json:"mediaType"`
+		Digest      string            `json:"digest"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"layers"`
+}
+
+// newOCISource returns the source for oci://registry/repo[:tag|@digest]
+// parseOCI splits u into the registry, repository and the tag or digest,
+// which defaults to latest
+func parseOCI(u *url.URL) (registry, repo, reference string) {
+	registry = u.Host
+	if registry == "docker.io" {
+		registry = "registry-1.docker.io"
+	}
+
+	repo = strings.TrimPrefix(u.Path, "/")
+	reference = ociDefaultTag
+	if name, digest, ok := strings.Cut(repo, "@"); ok {
+		repo, reference = name, digest
+	} else if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo, reference = repo[:i], repo[i+1:]
+	}
+
+	return registry, repo, reference
+}
+
+func newOCISource(u *url.URL, client *http.Client) *ociSource {
+	registry, repo, reference := parseOCI(u)
+
+	return &ociSource{
+		client:    client,
+		registry:  registry,
+		repo:      repo,
+		reference: reference,
+		username:  os.Getenv("OCI_USERNAME"),
+		password:  os.Getenv("OCI_PASSWORD"),
+	}
+}
+
+func (s *ociSource) files(ctx context.Context) ([]file, error) {
+	resp, err := s.get(ctx, "manifests/"+s.reference, ociManifestType+", "+dockerManifestType)
+	if err != nil {
+		return nil, fmt.Errorf("getting the manifest of %s: %w", s.repo, err)
+	}
+	defer resp.Body.Close()
+
+	var manifest ociManifest
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("reading the manifest of %s: %w", s.repo, err)
+	}
+	if manifest.MediaType != "" && manifest.MediaType != ociManifestType && manifest.MediaType != dockerManifestType {
+		return nil, fmt.Errorf("%s:%s is a %s, it must be an image manifest", s.repo, s.reference, manifest.MediaType)
+	}
+
+	files := make([]file, 0, len(manifest.Layers))
+	for _, l := range manifest.Layers {
+		f := file{
+			path:    l.Annotations[ociTitleAnnotation],
+			extract: l.Annotations[ociUnpackAnnotation] == "true",
+		}
+		if f.path == "" {
+			if !strings.Contains(l.MediaType, "tar") {
+				return nil, fmt.Errorf("layer %s of %s has no title and isn't a tar archive", l.Digest, s.repo)
+			}
+			f.extract = true
+		}
+		if sum, ok := strings.CutPrefix(l.Digest, "sha256:"); ok {
+			f.sha256 = sum
+		}
+
+		digest := l.Digest
+		f.request = func(ctx context.Context) (*http.Request, error) {
+			return s.request(ctx, "blobs/"+digest, "")
+		}
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+// get sends a request to the registry, authenticating if it is challenged
+func (s *ociSource) get(ctx context.Context, path, accept string) (*http.Response, error) {
+	req, err := s.request(ctx, path, accept)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		if err := s.setChallenge(resp.Header.Get("WWW-Authenticate")); err != nil {
+			return nil, err
+		}
+
+		if req, err = s.request(ctx, path, accept); err != nil {
+			return nil, err
+		}
+		if resp, err = s.client.Do(req); err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &statusError{status: resp.StatusCode}
+	}
+
+	return resp, nil
+}
+
+func (s *ociSource) request(ctx context.Context, path, accept string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("https://%s/v2/%s/%s", s.registry, s.repo, path), nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	auth, err := s.authorization(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	return req, nil
+}
+
+func (s *ociSource) setChallenge(header string) error {
+	scheme, rest, _ := strings.Cut(header, " ")
+	challenge := &ociChallenge{scheme: strings.ToLower(scheme), params: parseAuthParams(rest)}
+	if challenge.scheme != "bearer" && challenge.scheme != "basic" {
+		return fmt.Errorf("registry %s asked for unsupported authentication %q", s.registry, header)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.challenge = challenge
+	s.auth = ""
+
+	return nil
+}
+
+// authorization returns the Authorization header for the registry's
+// challenge, getting a new token when the previous one has expired
+func (s *ociSource) authorization(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.challenge == nil {
+		return "", nil
+	}
+	if s.challenge.scheme == "basic" {
+		if s.username == "" {
+			return "", fmt.Errorf("registry %s needs OCI_USERNAME and OCI_PASSWORD", s.registry)
+		}
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(s.username, s.password)
+		return req.Header.Get("Authorization"), nil
+	}
+	if s.auth != "" && time.Now().Before(s.expiry) {
+		return s.auth, nil
+	}
+
+	realm, err := url.Parse(s.challenge.params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("registry %s gave an invalid token realm %q", s.registry, s.challenge.params["realm"])
+	}
+	scope := s.challenge.params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", s.repo)
+	}
+	query := url.Values{"scope": {scope}}
+	if service := s.challenge.params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	resp, err := do(s.client, req)
+	if err != nil {
+		return "", fmt.Errorf("getting a token for %s: %w", s.registry, err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("getting a token for %s: %w", s.registry, err)
+	}
+	if result.Token == "" {
+		result.Token = result.AccessToken
+	}
+
+	expiry := ociDefaultTokenExpiry
+	if result.ExpiresIn > 0 {
+		expiry = time.Duration(result.ExpiresIn) * time.Second
+	}
+	// Leave time for the request to be sent
+	s.expiry = time.Now().Add(expiry * 9 / 10)
+	s.auth = "Bearer " + result.Token
+
+	return s.auth, nil
+}
+
+// parseAuthParams parses the comma separated key="value" params of a
+// WWW-Authenticate header, values may contain commas
+func parseAuthParams(s string) map[string]string {
+	params := map[string]string{}
+	for s = strings.TrimSpace(s); s != ""; {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				break
+			}
+			value, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		params[key] = value
+
+		s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), ","))
+	}
+
+	return params
+}
@@ -0,0 +1,140 @@
+package downloader
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	s3DefaultRegion   = "us-east-1"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// newS3Source returns the source for s3://bucket/key. It uses the standard
+// AWS environment variables:
+//
+//   - AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN sign the
+//     requests, which are anonymous without a key
+//   - AWS_REGION or AWS_DEFAULT_REGION, which defaults to us-east-1
+//   - AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL for an S3 compatible store
+//     such as MinIO, the bucket is then in the path rather than the host
+func newS3Source(u *url.URL, client *http.Client) (source, error) {
+	region := firstEnv("AWS_REGION", "AWS_DEFAULT_REGION")
+	if region == "" {
+		region = s3DefaultRegion
+	}
+
+	bucket := u.Host
+	endpoint := fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, region)
+	pathPrefix := ""
+	if e := firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"); e != "" {
+		endpoint = strings.TrimSuffix(e, "/")
+		pathPrefix = "/" + uriEscape(bucket)
+	}
+
+	signer := &s3Signer{
+		accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		token:     os.Getenv("AWS_SESSION_TOKEN"),
+		region:    region,
+	}
+
+	return &bucketSource{
+		client: client,
+		key:    strings.TrimPrefix(u.Path, "/"),
+		request: func(ctx context.Context, key string, query url.Values) (*http.Request, error) {
+			path := pathPrefix + "/" + escapePath(key)
+			rawQuery := encodeQuery(query)
+
+			target := endpoint + path
+			if rawQuery != "" {
+				target += "?" + rawQuery
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			signer.sign(req, path, rawQuery, time.Now().UTC())
+			return req, nil
+		},
+	}, nil
+}
+
+// s3Signer signs requests with AWS Signature Version 4
+type s3Signer struct {
+	accessKey string
+	secretKey string
+	token     string
+	region    string
+}
+
+// sign adds the signature to req, path and query must be escaped as they are
+// sent
+func (s *s3Signer) sign(req *http.Request, path, query string, now time.Time) {
+	if s.accessKey == "" || s.secretKey == "" {
+		return
+	}
+
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+
+	headers := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + s3UnsignedPayload + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	if s.token != "" {
+		req.Header.Set("X-Amz-Security-Token", s.token)
+		headers += "x-amz-security-token:" + s.token + "\n"
+		signedHeaders += ";x-amz-security-token"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, query, headers, signedHeaders, s3UnsignedPayload,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.region)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// firstEnv returns the first of the env vars which is set
+func firstEnv(names ...string) string {
+	for _, n := range names {
+		if v := os.Getenv(n); v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
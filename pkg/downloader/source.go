@@ -0,0 +1,110 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// The schemes of the URIs the downloader supports
+const (
+	SchemeHTTP  = "http"
+	SchemeHTTPS = "https"
+	SchemeS3    = "s3"
+	SchemeGCS   = "gs"
+	SchemeHF    = "hf"
+	SchemeOCI   = "oci"
+)
+
+// Schemes are the schemes of the URIs the downloader supports
+var Schemes = []string{SchemeHTTP, SchemeHTTPS, SchemeS3, SchemeGCS, SchemeHF, SchemeOCI}
+
+// Supported is true if the downloader can fetch uri
+func Supported(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+
+	return slices.Contains(Schemes, u.Scheme) && u.Host != ""
+}
+
+// IsDirectory is true if uri names several files, which are downloaded into
+// a directory. These are an S3 or GCS prefix, which is empty or ends in a
+// slash, a Hugging Face repository without a file and an OCI artifact.
+func IsDirectory(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+
+	switch u.Scheme {
+	case SchemeS3, SchemeGCS:
+		return u.Path == "" || strings.HasSuffix(u.Path, "/")
+	case SchemeHF:
+		_, _, file := parseHF(u)
+		return file == ""
+	case SchemeOCI:
+		return true
+	}
+
+	return false
+}
+
+// Revision is the revision of the model at uri which the URI names, the
+// revision of a Hugging Face repository or the tag or digest of an OCI
+// artifact. It is empty for other schemes.
+func Revision(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || !Supported(uri) {
+		return ""
+	}
+
+	switch u.Scheme {
+	case SchemeHF:
+		_, revision, _ := parseHF(u)
+		return revision
+	case SchemeOCI:
+		_, _, reference := parseOCI(u)
+		return reference
+	}
+
+	return ""
+}
+
+// newSource returns the source for u. Credentials are read from the
+// environment, see the documentation of each source.
+func newSource(u *url.URL, client *http.Client) (source, error) {
+	if !Supported(u.String()) {
+		return nil, fmt.Errorf("URL %s must have one of the schemes %s", u.Redacted(), strings.Join(Schemes, ", "))
+	}
+
+	switch u.Scheme {
+	case SchemeS3:
+		return newS3Source(u, client)
+	case SchemeGCS:
+		return newGCSSource(u, client)
+	case SchemeHF:
+		return newHFSource(u, client), nil
+	case SchemeOCI:
+		return newOCISource(u, client), nil
+	}
+
+	return httpSource{url: u.String()}, nil
+}
+
+// httpSource is a single file at an http(s) URL
+type httpSource struct {
+	url string
+}
+
+func (s httpSource) files(context.Context) ([]file, error) {
+	return []file{{
+		request: func(ctx context.Context) (*http.Request, error) {
+			return http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+		},
+	}}, nil
+}
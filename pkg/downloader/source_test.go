@@ -0,0 +1,226 @@
+package downloader_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/premAI-io/prem-operator/pkg/downloader"
+)
+
+var _ = DescribeTable("URI schemes",
+	func(uri string, supported, directory bool, revision string) {
+		Expect(downloader.Supported(uri)).To(Equal(supported))
+		Expect(downloader.IsDirectory(uri)).To(Equal(directory))
+		Expect(downloader.Revision(uri)).To(Equal(revision))
+	},
+	Entry("http file", "https://example.com/model.gguf", true, false, ""),
+	Entry("s3 object", "s3://models/llama/model.gguf", true, false, ""),
+	Entry("s3 prefix", "s3://models/llama/", true, true, ""),
+	Entry("s3 bucket", "s3://models", true, true, ""),
+	Entry("gcs prefix", "gs://models/llama/", true, true, ""),
+	Entry("hf repository", "hf://org/repo@v1", true, true, "v1"),
+	Entry("hf file", "hf://org/repo@v1/model.gguf", true, false, "v1"),
+	Entry("hf default revision", "hf://org/repo", true, true, "main"),
+	Entry("oci artifact", "oci://ghcr.io/org/model:v1", true, true, "v1"),
+	Entry("oci digest", "oci://ghcr.io/org/model@sha256:abc", true, true, "sha256:abc"),
+	Entry("oci default tag", "oci://localhost:5000/org/model", true, true, "latest"),
+	Entry("Hugging Face id", "org/repo", false, false, ""),
+	Entry("file", "file:///models/model.gguf", false, false, ""),
+)
+
+var _ = Describe("Sources", func() {
+	var (
+		dir     string
+		objects map[string]string
+	)
+
+	download := func(uri string, client *http.Client) error {
+		return downloader.Download(context.Background(), downloader.Options{
+			URL:    uri,
+			Output: dir,
+			Tries:  1,
+			Client: client,
+		})
+	}
+
+	expectFiles := func(files map[string]string) {
+		for name, content := range files {
+			Expect(os.ReadFile(filepath.Join(dir, name))).To(BeEquivalentTo(content))
+		}
+	}
+
+	// serveBucket serves objects like S3 and GCS's XML API with the bucket
+	// in the path, listing a single object per page
+	serveBucket := func(check func(r *http.Request)) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			check(r)
+
+			bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+			Expect(bucket).To(Equal("models"))
+			if key != "" {
+				content, ok := objects[key]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				fmt.Fprint(w, content)
+				return
+			}
+
+			prefix, marker := r.URL.Query().Get("prefix"), r.URL.Query().Get("marker")
+			keys := []string{}
+			for k := range objects {
+				if strings.HasPrefix(k, prefix) && k > marker {
+					keys = append(keys, k)
+				}
+			}
+			if len(keys) == 0 {
+				fmt.Fprint(w, `<ListBucketResult></ListBucketResult>`)
+				return
+			}
+			first := keys[0]
+			for _, k := range keys {
+				first = min(first, k)
+			}
+			fmt.Fprintf(w, `<ListBucketResult><IsTruncated>%t</IsTruncated><Contents><Key>%s</Key></Contents></ListBucketResult>`, len(keys) > 1, first)
+		}))
+		DeferCleanup(server.Close)
+
+		return server
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		objects = map[string]string{
+			"llama/config.json":   "{}",
+			"llama/model.bin":     "weights",
+			"llama/sub/extra.txt": "extra",
+			"other/model.bin":     "other",
+		}
+	})
+
+	It("signs S3 requests to a custom endpoint", func() {
+		requests := 0
+		server := serveBucket(func(r *http.Request) {
+			requests++
+			Expect(r.Header.Get("Authorization")).To(HavePrefix("AWS4-HMAC-SHA256 Credential=minio/"))
+			Expect(r.Header.Get("Authorization")).To(ContainSubstring("/eu-west-1/s3/aws4_request"))
+			Expect(r.Header.Get("X-Amz-Date")).NotTo(BeEmpty())
+		})
+		GinkgoT().Setenv("AWS_ENDPOINT_URL", server.URL)
+		GinkgoT().Setenv("AWS_REGION", "eu-west-1")
+		GinkgoT().Setenv("AWS_ACCESS_KEY_ID", "minio")
+		GinkgoT().Setenv("AWS_SECRET_ACCESS_KEY", "minio123")
+
+		Expect(download("s3://models/llama/", nil)).To(Succeed())
+		expectFiles(map[string]string{"config.json": "{}", "model.bin": "weights", "sub/extra.txt": "extra"})
+		Expect(filepath.Join(dir, "model.bin.part")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(dir, ".complete")).To(BeAnExistingFile())
+
+		By("skipping a directory which is already downloaded")
+		requests = 0
+		Expect(download("s3://models/llama/", nil)).To(Succeed())
+		Expect(requests).To(BeZero())
+	})
+
+	It("downloads a GCS object anonymously", func() {
+		server := serveBucket(func(r *http.Request) {
+			Expect(r.Header.Get("Authorization")).To(BeEmpty())
+		})
+		GinkgoT().Setenv("STORAGE_EMULATOR_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+		o := downloader.Options{URL: "gs://models/other/model.bin", Output: filepath.Join(dir, "model.bin")}
+		Expect(downloader.Download(context.Background(), o)).To(Succeed())
+		expectFiles(map[string]string{"model.bin": "other"})
+	})
+
+	It("downloads a Hugging Face repository at a revision", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Header.Get("Authorization")).To(Equal("Bearer hf_secret"))
+
+			switch r.URL.Path {
+			case "/api/models/org/repo/revision/v1":
+				fmt.Fprint(w, `{"sha": "abc123", "siblings": [{"rfilename": "config.json"}, {"rfilename": "weights/model.safetensors"}]}`)
+			case "/org/repo/resolve/abc123/config.json":
+				fmt.Fprint(w, "{}")
+			case "/org/repo/resolve/abc123/weights/model.safetensors":
+				fmt.Fprint(w, "weights")
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		DeferCleanup(server.Close)
+		GinkgoT().Setenv("HF_ENDPOINT", server.URL)
+		GinkgoT().Setenv("HF_TOKEN", "hf_secret")
+
+		Expect(download("hf://org/repo@v1", nil)).To(Succeed())
+		expectFiles(map[string]string{"config.json": "{}", "weights/model.safetensors": "weights"})
+	})
+
+	It("pulls an OCI artifact with a token", func() {
+		weights := "weights"
+		sum := sha256.Sum256([]byte(weights))
+		digest := "sha256:" + hex.EncodeToString(sum[:])
+
+		var server *httptest.Server
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/token" {
+				user, password, _ := r.BasicAuth()
+				Expect(user + ":" + password).To(Equal("robot:secret"))
+				Expect(r.URL.Query().Get("scope")).To(Equal("repository:org/model:pull"))
+				fmt.Fprint(w, `{"token": "registry-token"}`)
+				return
+			}
+
+			if r.Header.Get("Authorization") != "Bearer registry-token" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:org/model:pull"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			switch r.URL.Path {
+			case "/v2/org/model/manifests/v1":
+				w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+				Expect(json.NewEncoder(w).Encode(map[string]any{
+					"mediaType": "application/vnd.oci.image.manifest.v1+json",
+					"layers": []map[string]any{{
+						"mediaType":   "application/octet-stream",
+						"digest":      digest,
+						"annotations": map[string]string{"org.opencontainers.image.title": "model.gguf"},
+					}},
+				})).To(Succeed())
+			case "/v2/org/model/blobs/" + digest:
+				fmt.Fprint(w, weights)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		DeferCleanup(server.Close)
+		GinkgoT().Setenv("OCI_USERNAME", "robot")
+		GinkgoT().Setenv("OCI_PASSWORD", "secret")
+
+		host := strings.TrimPrefix(server.URL, "https://")
+		Expect(download("oci://"+host+"/org/model:v1", server.Client())).To(Succeed())
+		expectFiles(map[string]string{"model.gguf": weights})
+	})
+
+	It("rejects a checksum for a directory", func() {
+		err := downloader.Download(context.Background(), downloader.Options{
+			URL:    "s3://models/llama/",
+			Output: dir,
+			SHA256: strings.Repeat("a", 64),
+		})
+		Expect(downloader.ExitCode(err)).To(Equal(downloader.ExitUsage))
+	})
+})
@@ -67,6 +67,8 @@ set -e
 kubectl cluster-info --context kind-$CLUSTER_NAME
 echo "Sleep to give times to node to populate with all info"
 kubectl wait --for=condition=Ready node/$CLUSTER_NAME-control-plane
+# AIModelPrefetch downloads onto the node as the downloader's user
+docker exec $CLUSTER_NAME-control-plane sh -c 'mkdir -p /var/lib/prem-operator/models && chown 65532:65532 /var/lib/prem-operator/models'
 export EXTERNAL_IP=$(kubectl get nodes -o jsonpath='{.items[].status.addresses[?(@.type == "InternalIP")].address}')
 export BRIDGE_IP="172.18.0.1"
 kubectl get nodes -o wide
@@ -0,0 +1,110 @@
+package e2e_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	api "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("llamacpp test", func() {
+	var artifactName string
+	var sds, deps dynamic.ResourceInterface
+	var artifact *api.AIDeployment
+	var modelMap *api.AIModelMap
+	var startTime time.Time
+
+	JustBeforeEach(func() {
+		startTime = time.Now()
+		k8s := dynamic.NewForConfigOrDie(ctrl.GetConfigOrDie())
+
+		sds = k8s.Resource(schema.GroupVersionResource{Group: api.GroupVersion.Group, Version: api.GroupVersion.Version, Resource: "aideployments"}).Namespace("default")
+		deps = k8s.Resource(schema.GroupVersionResource{Group: appsv1.GroupName, Version: appsv1.SchemeGroupVersion.Version, Resource: "deployments"}).Namespace("default")
+
+		uArtifact := unstructured.Unstructured{}
+		uArtifact.Object, _ = runtime.DefaultUnstructuredConverter.ToUnstructured(artifact)
+		resp, err := sds.Create(context.TODO(), &uArtifact, metav1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		artifactName = resp.GetName()
+	})
+
+	AfterEach(func() {
+		err := sds.Delete(context.Background(), artifactName, metav1.DeleteOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = getTypedClient().Delete(context.Background(), modelMap)
+		Expect(err).ToNot(HaveOccurred())
+
+		checkLogs(startTime)
+	})
+
+	When("the model map sets a context size", func() {
+		BeforeEach(func() {
+			modelMap = createModelMapSingleEntry(api.AIEngineNameLlamacpp, "q4-k-m", api.AIModelSpec{
+				Uri:         "https://huggingface.co/TheBloke/TinyLlama-1.1B-Chat-v0.3-GGUF/resolve/main/tinyllama-1.1b-chat-v0.3.Q4_K_M.gguf",
+				ContextSize: 2048,
+			})
+
+			artifact = &api.AIDeployment{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "AIDeployment",
+					APIVersion: api.GroupVersion.String(),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "llamacpp-",
+				},
+				Spec: api.AIDeploymentSpec{
+					Engine: api.AIEngine{
+						Name: api.AIEngineNameLlamacpp,
+						Options: map[string]string{
+							constants.ThreadsKey: "4",
+						},
+					},
+					Endpoint: []api.Endpoint{{
+						Domain: "foo.127.0.0.1.nip.io",
+					}},
+					Models: []api.AIModel{{
+						ModelMapRef: &api.AIModelMapReference{
+							Name:    modelMap.Name,
+							Variant: "q4-k-m",
+						},
+					}},
+				},
+			}
+		})
+
+		It("downloads the model and passes the options to the server", func() {
+			Eventually(func(g Gomega) bool {
+				deployment := &appsv1.Deployment{}
+				if !getObjectWithName(deps, deployment, artifactName) {
+					return false
+				}
+
+				pod := deployment.Spec.Template.Spec
+				g.Expect(pod.InitContainers).To(HaveLen(1))
+				g.Expect(pod.InitContainers[0].Command).To(Equal([]string{"/downloader"}))
+				g.Expect(pod.InitContainers[0].Args).To(ContainElements("--url", modelMap.Spec.Llamacpp[0].Uri))
+
+				c := pod.Containers[0]
+				g.Expect(c.Name).To(Equal(constants.ContainerEngineName))
+				g.Expect(c.Image).To(HaveSuffix(":" + constants.ImageTagLlamacppDefault))
+				g.Expect(c.Args).To(ContainElements("--ctx-size", "2048"))
+				g.Expect(c.Args).To(ContainElements("--threads", "4"))
+				g.Expect(c.Args).ToNot(ContainElement("--n-gpu-layers"))
+				g.Expect(c.ReadinessProbe.HTTPGet.Path).To(Equal("/health"))
+
+				return true
+			}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(BeTrue())
+		})
+	})
+})
@@ -29,7 +29,7 @@ import (
 
 var _ = Describe("localai test", func() {
 	var artifactName string
-	var deps, sds, pods, svc, ingr dynamic.ResourceInterface
+	var deps, sds, pods, svc, ingr, cms dynamic.ResourceInterface
 	var scheme *runtime.Scheme
 	var artifact *api.AIDeployment
 	var startTime time.Time
@@ -43,6 +43,7 @@ var _ = Describe("localai test", func() {
 
 		sds = k8s.Resource(schema.GroupVersionResource{Group: api.GroupVersion.Group, Version: api.GroupVersion.Version, Resource: "aideployments"}).Namespace("default")
 		svc = k8s.Resource(schema.GroupVersionResource{Group: corev1.GroupName, Version: corev1.SchemeGroupVersion.Version, Resource: "services"}).Namespace("default")
+		cms = k8s.Resource(schema.GroupVersionResource{Group: corev1.GroupName, Version: corev1.SchemeGroupVersion.Version, Resource: "configmaps"}).Namespace("default")
 		ingr = k8s.Resource(schema.GroupVersionResource{Group: networkv1.GroupName, Version: corev1.SchemeGroupVersion.Version, Resource: "ingresses"}).Namespace("default")
 
 		pods = k8s.Resource(schema.GroupVersionResource{Group: corev1.GroupName, Version: corev1.SchemeGroupVersion.Version, Resource: "pods"}).Namespace("default")
@@ -326,6 +327,69 @@ var _ = Describe("localai test", func() {
 		})
 	})
 
+	When("we set the structured fields of a model", func() {
+		BeforeEach(func() {
+			artifact = &api.AIDeployment{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "AIDeployment",
+					APIVersion: api.GroupVersion.String(),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "localai-",
+				},
+				Spec: api.AIDeploymentSpec{
+					Engine: api.AIEngine{
+						Name: "localai",
+						Options: map[string]string{
+							constants.ThreadsKey: "4",
+						},
+					},
+					Endpoint: []api.Endpoint{{
+						Domain: "phi-2.127.0.0.1.nip.io",
+					}},
+					Models: []api.AIModel{{
+						AIModelSpec: api.AIModelSpec{
+							Uri:         "phi-2",
+							Backend:     "llama-cpp",
+							ContextSize: 2048,
+							DataType:    api.AIModelDataTypeFloat16,
+							Templates: &api.AIModelTemplates{
+								Chat: "chatml",
+							},
+						},
+					}},
+				},
+			}
+		})
+
+		It("generates the model config", func() {
+			Eventually(func(g Gomega) bool {
+				deployment := &appsv1.Deployment{}
+				if !getObjectWithName(deps, deployment, artifactName) {
+					return false
+				}
+
+				pod := deployment.Spec.Template.Spec
+				g.Expect(pod.Containers[0].Args).To(BeEmpty())
+				g.Expect(pod.InitContainers).To(HaveLen(1))
+
+				c := pod.InitContainers[0]
+				g.Expect(c.Args[0]).To(ContainSubstring(fmt.Sprintf("/models/%s-model.yaml", artifactName)))
+				g.Expect(c.Env).To(HaveLen(1))
+
+				config := c.Env[0].Value
+				g.Expect(config).To(ContainSubstring("backend: llama-cpp"))
+				g.Expect(config).To(ContainSubstring("context_size: 2048"))
+				g.Expect(config).To(ContainSubstring("f16: true"))
+				g.Expect(config).To(ContainSubstring("threads: 4"))
+				g.Expect(config).To(ContainSubstring("chat: chatml"))
+				g.Expect(config).To(ContainSubstring("model: phi-2"))
+
+				return true
+			}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(BeTrue())
+		})
+	})
+
 	When("we reference a model CRD", func() {
 		var modelMap *api.AIModelMap
 
@@ -458,7 +522,7 @@ var _ = Describe("localai test", func() {
 
 		BeforeEach(func() {
 			modelMap = createModelMapSingleEntry(api.AIEngineNameLocalai, "base", api.AIModelSpec{
-				Uri: " sentence-transformers/paraphrase-distilroberta-base-v1",
+				Uri: "sentence-transformers/paraphrase-distilroberta-base-v1",
 				EngineConfigFile: "---\n" +
 					"name: bert\n" +
 					"backend: sentencetransformers\n" +
@@ -593,4 +657,59 @@ var _ = Describe("localai test", func() {
 			}).WithPolling(5 * time.Second).WithTimeout(time.Hour).Should(ContainSubstring("bert"))
 		})
 	})
+
+	When("We specify a config in an inline model", func() {
+		BeforeEach(func() {
+			artifact = &api.AIDeployment{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "AIDeployment",
+					APIVersion: api.GroupVersion.String(),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "localai-",
+				},
+				Spec: api.AIDeploymentSpec{
+					Engine: api.AIEngine{
+						Name: "localai",
+					},
+					Endpoint: []api.Endpoint{{
+						Domain: "foo.127.0.0.1.nip.io",
+					}},
+					Models: []api.AIModel{{
+						AIModelSpec: api.AIModelSpec{
+							Uri: "sentence-transformers/paraphrase-distilroberta-base-v1",
+							EngineConfigFile: "---\n" +
+								"name: bert\n" +
+								"backend: sentencetransformers\n" +
+								"embeddings: true\n",
+						},
+					}},
+				},
+			}
+		})
+
+		It("creates a ConfigMap owned by the deployment and projects it", func() {
+			Eventually(func(g Gomega) bool {
+				cm := &corev1.ConfigMap{}
+				if !getObjectWithName(cms, cm, artifactName+"-inline-configs") {
+					return false
+				}
+
+				g.Expect(cm.OwnerReferences).To(ContainElement(HaveField("Name", artifactName)))
+				g.Expect(cm.Data).To(HaveKeyWithValue("localai-inline-engineConfigFile", ContainSubstring("name: bert")))
+
+				deployment := &appsv1.Deployment{}
+				if !getObjectWithName(deps, deployment, artifactName) {
+					return false
+				}
+
+				volumes := deployment.Spec.Template.Spec.Volumes
+				g.Expect(volumes).To(ContainElement(HaveField("Projected.Sources", ContainElement(
+					HaveField("ConfigMap.LocalObjectReference.Name", artifactName+"-inline-configs"),
+				))))
+
+				return true
+			}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(BeTrue())
+		})
+	})
 })
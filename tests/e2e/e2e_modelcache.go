@@ -0,0 +1,115 @@
+package e2e_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	api "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("ModelCache", func() {
+	var cache *api.ModelCache
+	var deployment *api.AIDeployment
+	var startTime time.Time
+
+	typedClient := getTypedClient()
+
+	BeforeEach(func() {
+		startTime = time.Now()
+
+		cache = &api.ModelCache{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "models-",
+			},
+			Spec: api.ModelCacheSpec{
+				Size:       resource.MustParse("1Gi"),
+				AccessMode: corev1.ReadWriteOnce,
+			},
+		}
+		Expect(typedClient.Create(context.Background(), cache)).To(Succeed())
+
+		deployment = &api.AIDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "vllm-cached-",
+			},
+			Spec: api.AIDeploymentSpec{
+				Engine: api.AIEngine{
+					Name: "vllm",
+				},
+				Models: []api.AIModel{{
+					AIModelSpec: api.AIModelSpec{Uri: "s3://models/opt-125m/"},
+				}},
+				Env: []corev1.EnvVar{{
+					Name:  "AWS_ENDPOINT_URL",
+					Value: "http://minio.minio.svc:9000",
+				}},
+				ModelCacheRef: &corev1.LocalObjectReference{Name: cache.Name},
+			},
+		}
+		Expect(typedClient.Create(context.Background(), deployment)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(typedClient.Delete(context.Background(), deployment)).To(Succeed())
+		Expect(typedClient.Delete(context.Background(), cache)).To(Succeed())
+
+		checkLogs(startTime)
+	})
+
+	It("downloads the model into the cache with a Job", func() {
+		Eventually(func(g Gomega) {
+			claim := &corev1.PersistentVolumeClaim{}
+			g.Expect(typedClient.Get(context.Background(), client.ObjectKey{Name: cache.Name}, claim)).To(Succeed())
+			g.Expect(claim.Spec.AccessModes).To(ConsistOf(corev1.ReadWriteOnce))
+
+			jobs := &batchv1.JobList{}
+			g.Expect(typedClient.List(context.Background(), jobs, client.MatchingLabels{
+				constants.ModelCacheLabel: cache.Name,
+			})).To(Succeed())
+			g.Expect(jobs.Items).To(HaveLen(1))
+
+			c := jobs.Items[0].Spec.Template.Spec.Containers[0]
+			g.Expect(c.Command).To(Equal([]string{"/downloader"}))
+			g.Expect(c.Args).To(ContainElements("--url", "s3://models/opt-125m/"))
+			g.Expect(c.Env).To(ContainElement(HaveField("Name", "AWS_ENDPOINT_URL")))
+
+			g.Expect(typedClient.Get(context.Background(), client.ObjectKeyFromObject(cache), cache)).To(Succeed())
+			g.Expect(cache.Status.Models).To(ConsistOf(And(
+				HaveField("Uri", "s3://models/opt-125m/"),
+				HaveField("Deployments", ConsistOf(deployment.Name)),
+			)))
+		}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(Succeed())
+	})
+
+	It("waits for the model before creating the Deployment", func() {
+		Eventually(func(g Gomega) {
+			g.Expect(typedClient.Get(context.Background(), client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+
+			cond := meta.FindStatusCondition(deployment.Status.Conditions, constants.ConditionModelsCached)
+			g.Expect(cond).NotTo(BeNil())
+
+			if cond.Status != metav1.ConditionTrue {
+				g.Expect(cond.Reason).To(BeElementOf(constants.ReasonDownloading, constants.ReasonDownloadFailed))
+				return
+			}
+
+			d := &appsv1.Deployment{}
+			g.Expect(typedClient.Get(context.Background(), client.ObjectKey{Name: deployment.Name}, d)).To(Succeed())
+			g.Expect(d.Spec.Template.Spec.InitContainers).To(BeEmpty())
+			g.Expect(d.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(And(
+				HaveField("MountPath", "/model-cache"),
+				HaveField("ReadOnly", true),
+			)))
+		}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(Succeed())
+	})
+})
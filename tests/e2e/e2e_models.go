@@ -71,14 +71,14 @@ var _ = Describe("AIModelMap", func() {
 							Variant: "variant1",
 							AIModelSpec: api.AIModelSpec{
 								Uri:              "s3://prem-ai/aimodels/variant1",
-								EngineConfigFile: "engine config file content",
+								EngineConfigFile: "name: variant1\n",
 							},
 						},
 						{
 							Variant: "variant2",
 							AIModelSpec: api.AIModelSpec{
 								Uri:              "s3://prem-ai/aimodels/variant2",
-								EngineConfigFile: "engine config file content",
+								EngineConfigFile: "name: variant2\n",
 							},
 						},
 					},
@@ -87,7 +87,7 @@ var _ = Describe("AIModelMap", func() {
 							Variant: "variant1",
 							AIModelSpec: api.AIModelSpec{
 								Uri:              "s3://prem-ai/aimodels/variant1",
-								EngineConfigFile: "engine config file content",
+								EngineConfigFile: "name: variant1\n",
 							},
 						},
 					},
@@ -116,9 +116,9 @@ var _ = Describe("AIModelMap", func() {
 					return false
 				}
 
-				g.Expect(aimodelmap.GetEngineConfigFileData(configMap, api.AIEngineNameLocalai, "variant1")).To(Equal("engine config file content"))
-				g.Expect(aimodelmap.GetEngineConfigFileData(configMap, api.AIEngineNameLocalai, "variant2")).To(Equal("engine config file content"))
-				g.Expect(aimodelmap.GetEngineConfigFileData(configMap, api.AIEngineNameVLLM, "variant1")).To(Equal("engine config file content"))
+				g.Expect(aimodelmap.GetEngineConfigFileData(configMap, api.AIEngineNameLocalai, "variant1")).To(Equal("name: variant1\n"))
+				g.Expect(aimodelmap.GetEngineConfigFileData(configMap, api.AIEngineNameLocalai, "variant2")).To(Equal("name: variant2\n"))
+				g.Expect(aimodelmap.GetEngineConfigFileData(configMap, api.AIEngineNameVLLM, "variant1")).To(Equal("name: variant1\n"))
 
 				return true
 			}, time.Minute, time.Second).Should(BeTrue())
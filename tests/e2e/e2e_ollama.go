@@ -0,0 +1,110 @@
+package e2e_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	api "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("ollama test", func() {
+	var artifactName string
+	var sds, deps dynamic.ResourceInterface
+	var artifact *api.AIDeployment
+	var modelMap *api.AIModelMap
+	var startTime time.Time
+
+	JustBeforeEach(func() {
+		startTime = time.Now()
+		k8s := dynamic.NewForConfigOrDie(ctrl.GetConfigOrDie())
+
+		sds = k8s.Resource(schema.GroupVersionResource{Group: api.GroupVersion.Group, Version: api.GroupVersion.Version, Resource: "aideployments"}).Namespace("default")
+		deps = k8s.Resource(schema.GroupVersionResource{Group: appsv1.GroupName, Version: appsv1.SchemeGroupVersion.Version, Resource: "deployments"}).Namespace("default")
+
+		uArtifact := unstructured.Unstructured{}
+		uArtifact.Object, _ = runtime.DefaultUnstructuredConverter.ToUnstructured(artifact)
+		resp, err := sds.Create(context.TODO(), &uArtifact, metav1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		artifactName = resp.GetName()
+	})
+
+	AfterEach(func() {
+		err := sds.Delete(context.Background(), artifactName, metav1.DeleteOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = getTypedClient().Delete(context.Background(), modelMap)
+		Expect(err).ToNot(HaveOccurred())
+
+		checkLogs(startTime)
+	})
+
+	When("there is a tag and a Modelfile", func() {
+		BeforeEach(func() {
+			modelMap = createModelMapSingleEntry(api.AIEngineNameOllama, "pirate", api.AIModelSpec{
+				Uri:              "tinyllama:1.1b",
+				EngineConfigFile: "FROM tinyllama:1.1b\nSYSTEM You are a pirate.\n",
+			})
+
+			artifact = &api.AIDeployment{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "AIDeployment",
+					APIVersion: api.GroupVersion.String(),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "ollama-",
+				},
+				Spec: api.AIDeploymentSpec{
+					Engine: api.AIEngine{
+						Name: api.AIEngineNameOllama,
+					},
+					Endpoint: []api.Endpoint{{
+						Domain: "foo.127.0.0.1.nip.io",
+					}},
+					Models: []api.AIModel{
+						{
+							AIModelSpec: api.AIModelSpec{
+								Uri: "tinyllama:1.1b",
+							},
+						},
+						{
+							ModelMapRef: &api.AIModelMapReference{
+								Name:    modelMap.Name,
+								Variant: "pirate",
+							},
+						},
+					},
+				},
+			}
+		})
+
+		It("pulls and creates the models before serving", func() {
+			Eventually(func(g Gomega) bool {
+				deployment := &appsv1.Deployment{}
+				if !getObjectWithName(deps, deployment, artifactName) {
+					return false
+				}
+
+				pod := deployment.Spec.Template.Spec
+				g.Expect(pod.InitContainers).To(HaveLen(1))
+				g.Expect(pod.InitContainers[0].Command).To(ContainElement("tinyllama:1.1b"))
+				g.Expect(pod.Volumes).To(ContainElement(HaveField("Name", "configs")))
+
+				c := pod.Containers[0]
+				g.Expect(c.Name).To(Equal(constants.ContainerEngineName))
+				g.Expect(c.ReadinessProbe.HTTPGet.Path).To(Equal("/api/version"))
+
+				return true
+			}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(BeTrue())
+		})
+	})
+})
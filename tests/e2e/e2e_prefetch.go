@@ -0,0 +1,128 @@
+package e2e_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	api "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("AIModelPrefetch", func() {
+	var modelMap *api.AIModelMap
+	var prefetch *api.AIModelPrefetch
+	var deployment *api.AIDeployment
+	var startTime time.Time
+
+	typedClient := getTypedClient()
+	env := []corev1.EnvVar{{
+		Name:  "AWS_ENDPOINT_URL",
+		Value: "http://minio.minio.svc:9000",
+	}}
+
+	BeforeEach(func() {
+		startTime = time.Now()
+
+		modelMap = createModelMapSingleEntry(api.AIEngineNameVLLM, "opt", api.AIModelSpec{
+			Uri: "s3://models/opt-125m/",
+		})
+
+		prefetch = &api.AIModelPrefetch{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "prefetch-",
+			},
+			Spec: api.AIModelPrefetchSpec{
+				Engine:    api.AIEngineNameVLLM,
+				Namespace: "default",
+				Models: []api.AIModelMapReference{{
+					Name:    modelMap.Name,
+					Variant: "opt",
+				}},
+				Env: env,
+			},
+		}
+		Expect(typedClient.Create(context.Background(), prefetch)).To(Succeed())
+
+		deployment = &api.AIDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "vllm-prefetched-",
+			},
+			Spec: api.AIDeploymentSpec{
+				Engine: api.AIEngine{
+					Name: api.AIEngineNameVLLM,
+				},
+				Models: []api.AIModel{{
+					ModelMapRef: &api.AIModelMapReference{
+						Name:    modelMap.Name,
+						Variant: "opt",
+					},
+				}},
+				Env: env,
+			},
+		}
+		Expect(typedClient.Create(context.Background(), deployment)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(typedClient.Delete(context.Background(), deployment)).To(Succeed())
+		Expect(typedClient.Delete(context.Background(), prefetch)).To(Succeed())
+		Expect(typedClient.Delete(context.Background(), modelMap)).To(Succeed())
+
+		checkLogs(startTime)
+	})
+
+	It("downloads the model onto the nodes with a DaemonSet", func() {
+		Eventually(func(g Gomega) {
+			ds := &appsv1.DaemonSet{}
+			g.Expect(typedClient.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: prefetch.Name}, ds)).To(Succeed())
+			g.Expect(ds.Labels).To(HaveKeyWithValue(constants.ModelPrefetchLabel, prefetch.Name))
+			g.Expect(*ds.Spec.Template.Spec.SecurityContext.RunAsNonRoot).To(BeTrue())
+
+			initContainers := ds.Spec.Template.Spec.InitContainers
+			g.Expect(initContainers).To(HaveLen(1))
+			g.Expect(initContainers[0].Command).To(Equal([]string{"/downloader"}))
+			g.Expect(initContainers[0].Args).To(ContainElements("--url", "s3://models/opt-125m/"))
+			g.Expect(initContainers[0].Env).To(ContainElement(HaveField("Name", "AWS_ENDPOINT_URL")))
+
+			g.Expect(typedClient.Get(context.Background(), client.ObjectKeyFromObject(prefetch), prefetch)).To(Succeed())
+			cond := meta.FindStatusCondition(prefetch.Status.Conditions, constants.ConditionModelsResolved)
+			g.Expect(cond).NotTo(BeNil())
+			g.Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+			g.Expect(prefetch.Status.Models).To(BeEquivalentTo(1))
+			g.Expect(prefetch.Status.Nodes).To(HaveLen(int(prefetch.Status.DesiredNodes)))
+			g.Expect(prefetch.Status.Nodes).NotTo(BeEmpty())
+		}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(Succeed())
+	})
+
+	It("prefers the nodes which have the model for the engine", func() {
+		Eventually(func(g Gomega) {
+			g.Expect(typedClient.Get(context.Background(), client.ObjectKeyFromObject(prefetch), prefetch)).To(Succeed())
+			g.Expect(prefetch.Status.ReadyNodes).To(BeNumerically(">", 0))
+
+			d := &appsv1.Deployment{}
+			g.Expect(typedClient.Get(context.Background(), client.ObjectKey{Name: deployment.Name}, d)).To(Succeed())
+
+			pod := d.Spec.Template.Spec
+			g.Expect(pod.Affinity).NotTo(BeNil())
+			g.Expect(pod.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(
+				ContainElement(HaveField("Preference.MatchExpressions", ContainElement(
+					HaveField("Operator", corev1.NodeSelectorOpExists),
+				))),
+			)
+
+			g.Expect(pod.InitContainers).To(HaveLen(1))
+			g.Expect(pod.InitContainers[0].Args).To(ContainElement("--from"))
+			g.Expect(pod.Containers[0].VolumeMounts).To(ContainElement(And(
+				HaveField("MountPath", "/var/lib/prem-operator"),
+				HaveField("ReadOnly", true),
+			)))
+		}).WithPolling(5 * time.Second).WithTimeout(5 * time.Minute).Should(Succeed())
+	})
+})
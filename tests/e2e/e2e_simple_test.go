@@ -7,8 +7,10 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	api "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/constants"
 	"github.com/premAI-io/prem-operator/controllers/resources"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -80,5 +82,29 @@ var _ = Describe("simple test", func() {
 
 			return found
 		}).WithTimeout(time.Minute).Should(BeTrue())
+
+		By("reporting the progress in the status conditions")
+		Eventually(func(g Gomega) bool {
+			u, err := sds.Get(context.TODO(), artifactName, metav1.GetOptions{})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			sd := &api.AIDeployment{}
+			err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, sd)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(sd.Status.ObservedGeneration).To(Equal(sd.Generation))
+
+			for _, t := range []string{
+				constants.ConditionModelsResolved,
+				constants.ConditionEngineConfigured,
+				constants.ConditionServiceReady,
+				constants.ConditionIngressReady,
+			} {
+				if !meta.IsStatusConditionTrue(sd.Status.Conditions, t) {
+					return false
+				}
+			}
+
+			return true
+		}).WithTimeout(time.Minute).Should(BeTrue())
 	})
 })
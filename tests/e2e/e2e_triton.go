@@ -0,0 +1,130 @@
+package e2e_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	api "github.com/premAI-io/prem-operator/api/v1alpha1"
+	"github.com/premAI-io/prem-operator/controllers/constants"
+	"github.com/premAI-io/prem-operator/controllers/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("triton test", func() {
+	var artifactName string
+	var sds, deps, svc dynamic.ResourceInterface
+	var artifact *api.AIDeployment
+	var modelMap *api.AIModelMap
+	var startTime time.Time
+
+	JustBeforeEach(func() {
+		startTime = time.Now()
+		k8s := dynamic.NewForConfigOrDie(ctrl.GetConfigOrDie())
+
+		sds = k8s.Resource(schema.GroupVersionResource{Group: api.GroupVersion.Group, Version: api.GroupVersion.Version, Resource: "aideployments"}).Namespace("default")
+		deps = k8s.Resource(schema.GroupVersionResource{Group: appsv1.GroupName, Version: appsv1.SchemeGroupVersion.Version, Resource: "deployments"}).Namespace("default")
+		svc = k8s.Resource(schema.GroupVersionResource{Group: v1.GroupName, Version: v1.SchemeGroupVersion.Version, Resource: "services"}).Namespace("default")
+
+		uArtifact := unstructured.Unstructured{}
+		uArtifact.Object, _ = runtime.DefaultUnstructuredConverter.ToUnstructured(artifact)
+		resp, err := sds.Create(context.TODO(), &uArtifact, metav1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		artifactName = resp.GetName()
+	})
+
+	AfterEach(func() {
+		err := sds.Delete(context.Background(), artifactName, metav1.DeleteOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = getTypedClient().Delete(context.Background(), modelMap)
+		Expect(err).ToNot(HaveOccurred())
+
+		checkLogs(startTime)
+	})
+
+	When("a model is an ensemble", func() {
+		BeforeEach(func() {
+			modelMap = createModelMapSingleEntry(api.AIEngineNameTriton, "ensemble", api.AIModelSpec{
+				EngineConfigFile: "platform: \"ensemble\"\n",
+			})
+
+			artifact = &api.AIDeployment{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "AIDeployment",
+					APIVersion: api.GroupVersion.String(),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "triton-",
+				},
+				Spec: api.AIDeploymentSpec{
+					Engine: api.AIEngine{
+						Name: api.AIEngineNameTriton,
+					},
+					Endpoint: []api.Endpoint{{
+						Domain: "foo.127.0.0.1.nip.io",
+					}},
+					Models: []api.AIModel{{
+						ModelMapRef: &api.AIModelMapReference{
+							Name:    modelMap.Name,
+							Variant: "ensemble",
+						},
+					}},
+				},
+			}
+		})
+
+		It("adds its config to the model repository", func() {
+			Eventually(func(g Gomega) bool {
+				deployment := &appsv1.Deployment{}
+				if !getObjectWithName(deps, deployment, artifactName) {
+					return false
+				}
+
+				pod := deployment.Spec.Template.Spec
+				g.Expect(pod.Volumes).To(ContainElement(HaveField("Name", "configs")))
+				g.Expect(pod.InitContainers).To(HaveLen(1))
+
+				init := pod.InitContainers[0]
+				g.Expect(init.Env).To(ContainElement(v1.EnvVar{Name: "MODEL_DIR", Value: "/models/" + modelMap.Name + "-ensemble"}))
+				g.Expect(init.Env).To(ContainElement(v1.EnvVar{Name: "MODEL_VERSION", Value: "1"}))
+				g.Expect(init.Env).To(ContainElement(v1.EnvVar{Name: "CONFIG_FILE", Value: "/configs/engine/" + modelMap.Name + "-ensemble.pbtxt"}))
+				g.Expect(init.VolumeMounts).To(ContainElement(HaveField("Name", "configs")))
+
+				g.Expect(pod.Containers[0].Name).To(Equal(constants.ContainerEngineName))
+
+				return true
+			}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(BeTrue())
+		})
+
+		It("exposes the gRPC and metrics ports", func() {
+			Eventually(func(g Gomega) bool {
+				service := &v1.Service{}
+				if !getObjectWithAnnotation(svc, service, resources.DefaultAnnotation, artifactName) {
+					return false
+				}
+
+				g.Expect(service.Spec.Ports).To(HaveLen(3))
+				g.Expect(service.Spec.Ports).To(ContainElement(And(
+					HaveField("Name", "grpc"),
+					HaveField("Port", int32(8001)),
+					HaveField("AppProtocol", HaveValue(Equal("grpc"))),
+				)))
+				g.Expect(service.Spec.Ports).To(ContainElement(And(
+					HaveField("Name", "metrics"),
+					HaveField("Port", int32(8002)),
+				)))
+
+				return true
+			}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(BeTrue())
+		})
+	})
+})
@@ -2,6 +2,7 @@ package e2e_test
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -24,7 +25,7 @@ import (
 
 var _ = Describe("vllm test", func() {
 	var artifactName string
-	var deps, sds, pods dynamic.ResourceInterface
+	var deps, sts, svcs, sds, pods dynamic.ResourceInterface
 	var scheme *runtime.Scheme
 	var artifact *api.AIDeployment
 	var startTime time.Time
@@ -45,6 +46,8 @@ var _ = Describe("vllm test", func() {
 		sds = k8s.Resource(schema.GroupVersionResource{Group: api.GroupVersion.Group, Version: api.GroupVersion.Version, Resource: "aideployments"}).Namespace("default")
 		pods = k8s.Resource(schema.GroupVersionResource{Group: corev1.GroupName, Version: corev1.SchemeGroupVersion.Version, Resource: "pods"}).Namespace("default")
 		deps = k8s.Resource(schema.GroupVersionResource{Group: appsv1.GroupName, Version: appsv1.SchemeGroupVersion.Version, Resource: "deployments"}).Namespace("default")
+		sts = k8s.Resource(schema.GroupVersionResource{Group: appsv1.GroupName, Version: appsv1.SchemeGroupVersion.Version, Resource: "statefulsets"}).Namespace("default")
+		svcs = k8s.Resource(schema.GroupVersionResource{Group: corev1.GroupName, Version: corev1.SchemeGroupVersion.Version, Resource: "services"}).Namespace("default")
 
 		uArtifact := unstructured.Unstructured{}
 		uArtifact.Object, _ = runtime.DefaultUnstructuredConverter.ToUnstructured(artifact)
@@ -247,6 +250,292 @@ var _ = Describe("vllm test", func() {
 			})
 		})
 
+		When("We request several GPUs", func() {
+			BeforeEach(func() {
+				artifact = &api.AIDeployment{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "AIDeployment",
+						APIVersion: api.GroupVersion.String(),
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						GenerateName: "vllm-",
+					},
+					Spec: api.AIDeploymentSpec{
+						Engine: api.AIEngine{
+							Name: "vllm",
+						},
+						Endpoint: []api.Endpoint{{
+							Domain: "foo.127.0.0.1.nip.io",
+						}},
+						Models: custModel,
+						Deployment: api.Deployment{
+							Accelerator: &api.Accelerator{
+								Interface: api.AcceleratorInterfaceCUDA,
+							},
+							Resources: corev1.ResourceRequirements{
+								Requests: map[corev1.ResourceName]resource.Quantity{
+									constants.NvidiaGPULabel: resource.MustParse("4"),
+								},
+							},
+						},
+					},
+				}
+			})
+
+			It("splits the model across them", func() {
+				Eventually(func(g Gomega) bool {
+					deployment := &appsv1.Deployment{}
+					if !getObjectWithName(deps, deployment, artifactName) {
+						return false
+					}
+
+					pod := deployment.Spec.Template.Spec
+					c := pod.Containers[0]
+					g.Expect(c.Args).To(ContainElements("--tensor-parallel-size", "4"))
+					g.Expect(c.VolumeMounts).To(ContainElement(HaveField("MountPath", "/dev/shm")))
+					g.Expect(pod.Volumes).To(ContainElement(HaveField("EmptyDir.Medium", corev1.StorageMediumMemory)))
+
+					return true
+				}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(BeTrue())
+			})
+		})
+
+		When("We split a replica across several pods", func() {
+			BeforeEach(func() {
+				replicas := int32(2)
+				artifact = &api.AIDeployment{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "AIDeployment",
+						APIVersion: api.GroupVersion.String(),
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						GenerateName: "vllm-",
+					},
+					Spec: api.AIDeploymentSpec{
+						Engine: api.AIEngine{
+							Name: "vllm",
+						},
+						Endpoint: []api.Endpoint{{
+							Domain: "foo.127.0.0.1.nip.io",
+						}},
+						Models: custModel,
+						Deployment: api.Deployment{
+							Replicas: &replicas,
+							MultiNode: &api.MultiNode{
+								Size: 3,
+							},
+						},
+					},
+				}
+			})
+
+			It("creates a leader and a group of workers for each replica", func() {
+				Eventually(func(g Gomega) bool {
+					leaders := &appsv1.StatefulSet{}
+					if !getObjectWithName(sts, leaders, artifactName) {
+						return false
+					}
+
+					g.Expect(*leaders.Spec.Replicas).To(Equal(int32(2)))
+					g.Expect(leaders.Spec.Template.Labels).To(HaveKeyWithValue(constants.MultiNodeRoleLabel, constants.MultiNodeRoleLeader))
+					c := leaders.Spec.Template.Spec.Containers[0]
+					g.Expect(c.Args).To(ContainElements("--pipeline-parallel-size", "3"))
+					g.Expect(c.Env).To(ContainElement(corev1.EnvVar{Name: constants.EnvGroupSize, Value: "3"}))
+
+					for i := 0; i < 2; i++ {
+						workers := &appsv1.StatefulSet{}
+						if !getObjectWithName(sts, workers, fmt.Sprintf("%s-worker-%d", artifactName, i)) {
+							return false
+						}
+
+						g.Expect(*workers.Spec.Replicas).To(Equal(int32(2)))
+						c := workers.Spec.Template.Spec.Containers[0]
+						g.Expect(c.ReadinessProbe).To(BeNil())
+						g.Expect(c.Env).To(ContainElement(corev1.EnvVar{
+							Name:  constants.EnvLeaderAddress,
+							Value: fmt.Sprintf("%s-%d.%s-group", artifactName, i, artifactName),
+						}))
+					}
+
+					svc := &corev1.Service{}
+					if !getObjectWithName(svcs, svc, artifactName) {
+						return false
+					}
+					g.Expect(svc.Spec.Selector).To(HaveKeyWithValue(constants.MultiNodeRoleLabel, constants.MultiNodeRoleLeader))
+
+					deployment := &appsv1.Deployment{}
+					g.Expect(getObjectWithName(deps, deployment, artifactName)).To(BeFalse())
+
+					return true
+				}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(BeTrue())
+			})
+		})
+
+		When("We set a pod template and the image pull policy", func() {
+			BeforeEach(func() {
+				artifact = &api.AIDeployment{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "AIDeployment",
+						APIVersion: api.GroupVersion.String(),
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						GenerateName: "vllm-",
+					},
+					Spec: api.AIDeploymentSpec{
+						Engine: api.AIEngine{
+							Name: "vllm",
+							Options: map[string]string{
+								constants.ImagePullPolicyKey: string(corev1.PullIfNotPresent),
+							},
+						},
+						Endpoint: []api.Endpoint{{
+							Domain: "foo.127.0.0.1.nip.io",
+						}},
+						Models: custModel,
+						Deployment: api.Deployment{
+							PodTemplate: &corev1.PodTemplateSpec{
+								Spec: corev1.PodSpec{
+									Containers: []corev1.Container{{
+										Name:    "sidecar",
+										Image:   "busybox",
+										Command: []string{"sleep", "infinity"},
+									}},
+									ImagePullSecrets: []corev1.LocalObjectReference{{Name: "regcred"}},
+									Tolerations: []corev1.Toleration{{
+										Key:      "dedicated",
+										Operator: corev1.TolerationOpExists,
+										Effect:   corev1.TaintEffectNoSchedule,
+									}},
+								},
+							},
+						},
+					},
+				}
+			})
+
+			It("keeps the template's settings", func() {
+				Eventually(func(g Gomega) bool {
+					deployment := &appsv1.Deployment{}
+					if !getObjectWithName(deps, deployment, artifactName) {
+						return false
+					}
+
+					pod := deployment.Spec.Template.Spec
+					g.Expect(pod.Containers).To(HaveLen(2))
+					g.Expect(pod.Containers[0].Name).To(Equal("sidecar"))
+					g.Expect(pod.Containers[1].Name).To(Equal(constants.ContainerEngineName))
+					g.Expect(pod.Containers[1].ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+					g.Expect(pod.ImagePullSecrets).To(ContainElement(corev1.LocalObjectReference{Name: "regcred"}))
+					g.Expect(pod.Tolerations).To(ContainElement(HaveField("Key", "dedicated")))
+
+					return true
+				}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(BeTrue())
+			})
+		})
+
+		When("the model is in an S3 bucket", func() {
+			BeforeEach(func() {
+				artifact = &api.AIDeployment{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "AIDeployment",
+						APIVersion: api.GroupVersion.String(),
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						GenerateName: "vllm-",
+					},
+					Spec: api.AIDeploymentSpec{
+						Engine: api.AIEngine{
+							Name: "vllm",
+						},
+						Endpoint: []api.Endpoint{{
+							Domain: "foo.127.0.0.1.nip.io",
+						}},
+						Models: []api.AIModel{{
+							AIModelSpec: api.AIModelSpec{Uri: "s3://models/opt-125m/"},
+						}},
+						Env: []corev1.EnvVar{{
+							Name:  "AWS_ENDPOINT_URL",
+							Value: "http://minio.minio.svc:9000",
+						}},
+					},
+				}
+			})
+
+			It("downloads it before starting the engine", func() {
+				Eventually(func(g Gomega) bool {
+					deployment := &appsv1.Deployment{}
+					if !getObjectWithName(deps, deployment, artifactName) {
+						return false
+					}
+
+					pod := deployment.Spec.Template.Spec
+					g.Expect(pod.InitContainers).To(HaveLen(1))
+					init := pod.InitContainers[0]
+					g.Expect(init.Command).To(Equal([]string{"/downloader"}))
+					g.Expect(init.Args).To(ContainElements("--url", "s3://models/opt-125m/"))
+					g.Expect(init.Env).To(ContainElement(HaveField("Name", "AWS_ENDPOINT_URL")))
+
+					c := pod.Containers[0]
+					modelDir := "/downloads/" + artifactName + "-model"
+					g.Expect(init.Args).To(ContainElements("--output", modelDir))
+					g.Expect(c.Args).To(ContainElements("--model", modelDir))
+					g.Expect(c.Args).To(ContainElements("--served-model-name", artifactName+"-model"))
+					g.Expect(c.VolumeMounts).To(ContainElement(HaveField("MountPath", "/downloads")))
+
+					return true
+				}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(BeTrue())
+			})
+		})
+
+		When("the model has a credentials secret", func() {
+			BeforeEach(func() {
+				artifact = &api.AIDeployment{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "AIDeployment",
+						APIVersion: api.GroupVersion.String(),
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						GenerateName: "vllm-",
+					},
+					Spec: api.AIDeploymentSpec{
+						Engine: api.AIEngine{
+							Name: "vllm",
+						},
+						Endpoint: []api.Endpoint{{
+							Domain: "foo.127.0.0.1.nip.io",
+						}},
+						Models: []api.AIModel{
+							{
+								AIModelSpec: api.AIModelSpec{
+									Uri:                  "s3://models/opt-125m/",
+									CredentialsSecretRef: &corev1.LocalObjectReference{Name: "minio-credentials"},
+								},
+							},
+						},
+					},
+				}
+			})
+
+			It("gives the secret only to the downloader", func() {
+				Eventually(func(g Gomega) bool {
+					deployment := &appsv1.Deployment{}
+					if !getObjectWithName(deps, deployment, artifactName) {
+						return false
+					}
+
+					pod := deployment.Spec.Template.Spec
+					g.Expect(pod.InitContainers).To(HaveLen(1))
+					g.Expect(pod.InitContainers[0].EnvFrom).To(ConsistOf(
+						HaveField("SecretRef.Name", "minio-credentials"),
+					))
+					g.Expect(pod.Containers[0].EnvFrom).To(BeEmpty())
+					g.Expect(pod.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "HF_TOKEN")))
+
+					return true
+				}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(BeTrue())
+			})
+		})
+
 		When("We specify AWQ in engine options", func() {
 			BeforeEach(func() {
 				artifact = &api.AIDeployment{
@@ -354,6 +643,27 @@ var _ = Describe("vllm test", func() {
 				}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(BeTrue())
 			})
 
+			It("updates the deployment when the model map changes", func() {
+				tc := getTypedClient()
+				Eventually(func(g Gomega) {
+					g.Expect(tc.Get(context.Background(), client.ObjectKeyFromObject(modelMap), modelMap)).To(Succeed())
+					modelMap.Spec.Vllm[0].Uri = "microsoft/phi-1_5"
+					g.Expect(tc.Update(context.Background(), modelMap)).To(Succeed())
+				}).WithTimeout(time.Minute).Should(Succeed())
+
+				By("rolling out the new model")
+				Eventually(func(g Gomega) bool {
+					deployment := &appsv1.Deployment{}
+					if !getObjectWithName(deps, deployment, artifactName) {
+						return false
+					}
+
+					c := deployment.Spec.Template.Spec.Containers[0]
+					g.Expect(c.Args).To(Equal([]string{"--model", "microsoft/phi-1_5"}))
+					return true
+				}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(BeTrue())
+			})
+
 			AfterEach(func() {
 				c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
 				Expect(err).ToNot(HaveOccurred())
@@ -362,5 +672,71 @@ var _ = Describe("vllm test", func() {
 			})
 
 		})
+
+		When("a model is an adapter of the base model", func() {
+			var modelMap *api.AIModelMap
+
+			BeforeEach(func() {
+				modelMap = createModelMapSingleEntry(api.AIEngineNameVLLM, "sql", api.AIModelSpec{
+					Uri:       "yard1/llama-2-7b-sql-lora-test",
+					AdapterOf: "meta-llama/Llama-2-7b-hf",
+				})
+
+				artifact = &api.AIDeployment{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "AIDeployment",
+						APIVersion: api.GroupVersion.String(),
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						GenerateName: string(api.AIEngineNameVLLM) + "-",
+					},
+					Spec: api.AIDeploymentSpec{
+						Engine: api.AIEngine{
+							Name: api.AIEngineNameVLLM,
+						},
+						Endpoint: []api.Endpoint{{
+							Domain: "foo.127.0.0.1.nip.io",
+						}},
+						Models: []api.AIModel{
+							{
+								AIModelSpec: api.AIModelSpec{
+									Uri: "meta-llama/Llama-2-7b-hf",
+								},
+							},
+							{
+								ModelMapRef: &api.AIModelMapReference{
+									Name:    modelMap.Name,
+									Variant: "sql",
+								},
+							},
+						},
+					},
+				}
+			})
+
+			It("downloads the adapter and enables LoRA", func() {
+				Eventually(func(g Gomega) bool {
+					deployment := &appsv1.Deployment{}
+					if !getObjectWithName(deps, deployment, artifactName) {
+						return false
+					}
+
+					adapter := modelMap.Name + "-sql"
+					pod := deployment.Spec.Template.Spec
+					g.Expect(pod.InitContainers).To(HaveLen(1))
+					g.Expect(pod.InitContainers[0].Env).To(ContainElement(corev1.EnvVar{Name: "ADAPTER_URI", Value: "yard1/llama-2-7b-sql-lora-test"}))
+
+					c := pod.Containers[0]
+					g.Expect(c.Args).To(ContainElements("--model", "meta-llama/Llama-2-7b-hf"))
+					g.Expect(c.Args).To(ContainElements("--enable-lora", "--lora-modules", adapter+"=/adapters/"+adapter))
+					return true
+				}).WithPolling(5 * time.Second).WithTimeout(time.Minute).Should(BeTrue())
+			})
+
+			AfterEach(func() {
+				err := getTypedClient().Delete(context.Background(), modelMap)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
 	})
 })
@@ -10,6 +10,8 @@ import (
 	. "github.com/onsi/gomega"
 	api "github.com/premAI-io/prem-operator/api/v1alpha1"
 
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -124,6 +126,10 @@ func getTypedClient() client.Client {
 	Expect(err).ToNot(HaveOccurred())
 	err = corev1.AddToScheme(scheme)
 	Expect(err).ToNot(HaveOccurred())
+	err = appsv1.AddToScheme(scheme)
+	Expect(err).ToNot(HaveOccurred())
+	err = batchv1.AddToScheme(scheme)
+	Expect(err).ToNot(HaveOccurred())
 	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
 	Expect(err).ToNot(HaveOccurred())
 	return client.NewNamespacedClient(c, "default")
@@ -154,6 +160,14 @@ func createModelMapSingleEntry(name api.AIEngineName, variantName string, spec a
 		modelMap.Spec.Vllm = variants
 	case api.AIEngineNameDeepSpeedMii:
 		modelMap.Spec.DeepSpeedMii = variants
+	case api.AIEngineNameOllama:
+		modelMap.Spec.Ollama = variants
+	case api.AIEngineNameLlamacpp:
+		modelMap.Spec.Llamacpp = variants
+	case api.AIEngineNameSglang:
+		modelMap.Spec.Sglang = variants
+	case api.AIEngineNameTriton:
+		modelMap.Spec.TensorRT = variants
 	}
 
 	c := getTypedClient()